@@ -0,0 +1,167 @@
+// atl-cmt-diff-env compares two CMT environments saved with atl-cmt-save-env
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/atlas-org/scripts/cmtenv"
+)
+
+var g_verbose = flag.Bool("v", false, "enable verbose output")
+var g_dir = flag.String("d", ".", "directory to relocate both environments to")
+var g_help = flag.Bool("h", false, "print help")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(
+			os.Stderr,
+			`$ %s [options] OLD-CACHE NEW-CACHE
+
+ex:
+ $ %s old.cmt new.cmt
+ $ %s store.cmt#rel1 store.cmt#rel2
+
+options:
+`,
+			os.Args[0], os.Args[0], os.Args[0],
+		)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *g_help {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if flag.NArg() != 2 {
+		fmt.Fprintf(os.Stderr, "**error** you need to give 2 CMT environment caches\n")
+		fmt.Fprintf(os.Stderr, "**error** got [%d]: %v\n", flag.NArg(), flag.Args())
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	if *g_dir == "." {
+		*g_dir, err = os.Getwd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "**error** getting workdir: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	old, err := load(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** opening cache [%s]: %v\n", flag.Arg(0), err)
+		os.Exit(2)
+	}
+
+	new, err := load(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** opening cache [%s]: %v\n", flag.Arg(1), err)
+		os.Exit(2)
+	}
+
+	ndiffs := diff_env(os.Stdout, old, new)
+	if *g_verbose {
+		fmt.Fprintf(os.Stderr, "::: found [%d] variables which are different\n", ndiffs)
+	}
+	if ndiffs > 0 {
+		os.Exit(1)
+	}
+}
+
+// load returns the environment referenced by ref (FILE or FILE#NAME)
+func load(ref string) (map[string]string, error) {
+	cache, err := cmtenv.OpenRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	setup, err := cache.Setup(*g_dir, *g_verbose)
+	if err != nil {
+		return nil, err
+	}
+	defer setup.Delete()
+
+	env := make(map[string]string)
+	for k, v := range setup.EnvMap() {
+		if k == "_" {
+			continue
+		}
+		env[k] = v
+	}
+	return env, nil
+}
+
+// diff_env writes the differences between old and new into w and
+// returns the number of variables which differ.
+func diff_env(w io.Writer, old, new map[string]string) int {
+	keys := make([]string, 0, len(old)+len(new))
+	for k := range old {
+		keys = append(keys, k)
+	}
+	for k := range new {
+		if _, dup := old[k]; !dup {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	n := 0
+	for _, k := range keys {
+		vold, inold := old[k]
+		vnew, innew := new[k]
+		switch {
+		case !inold:
+			fmt.Fprintf(w, "+ %s=%q\n", k, vnew)
+		case !innew:
+			fmt.Fprintf(w, "- %s=%q\n", k, vold)
+		case vold == vnew:
+			continue
+		case cmtenv.IsPathList(k):
+			fmt.Fprintf(w, "~ %s\n", k)
+			diff_pathlist(w, cmtenv.SplitPathList(vold), cmtenv.SplitPathList(vnew))
+		default:
+			fmt.Fprintf(w, "~ %s=%q -> %q\n", k, vold, vnew)
+		}
+		n++
+	}
+	return n
+}
+
+// diff_pathlist writes the entries removed from and added to a path-list
+func diff_pathlist(w io.Writer, old, new []string) {
+	inold := make(map[string]bool, len(old))
+	for _, p := range old {
+		inold[p] = true
+	}
+	innew := make(map[string]bool, len(new))
+	for _, p := range new {
+		innew[p] = true
+	}
+
+	n := 0
+	for _, p := range old {
+		if !innew[p] {
+			fmt.Fprintf(w, "    - %s\n", p)
+			n++
+		}
+	}
+	for _, p := range new {
+		if !inold[p] {
+			fmt.Fprintf(w, "    + %s\n", p)
+			n++
+		}
+	}
+	if n == 0 {
+		fmt.Fprintf(w, "    (same entries, different order)\n")
+	}
+}
+
+// EOF
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atlas-org/scripts/cmtenv"
+	"github.com/atlas-org/scripts/findlib"
+)
+
+// g_path_kinds holds the kinds of files of the path-list variables findlib
+// knows about, whose entries are split on the separators of the kind.
+var g_path_kinds = []*findlib.Kind{
+	findlib.Library,
+	findlib.Python,
+	findlib.JobOptions,
+	findlib.Data,
+}
+
+// g_file_paths holds the path-list variables whose entries may also be
+// files (zip archives, eggs, jars).
+var g_file_paths = map[string]bool{
+	"PYTHONPATH": true,
+	"CLASSPATH":  true,
+}
+
+// split_paths splits the path-list value v of k into its entries.
+func split_paths(k, v string) []string {
+	for _, kind := range g_path_kinds {
+		if kind.EnvVar == k {
+			return findlib.SplitPathList(v, kind.Seps)
+		}
+	}
+	return cmtenv.SplitPathList(v)
+}
+
+// check_env verifies the entries of all path-list variables of env are
+// existing directories (or files, for the variables of g_file_paths).
+// Entries still located under orig, the directory the environment was
+// saved from, are reported as not relocated.
+// check_env returns the number of dangling entries.
+func check_env(w io.Writer, env map[string]string, orig string) int {
+	ndangling := 0
+	nstale := 0
+	for _, k := range env_keys(env) {
+		if !cmtenv.IsPathList(k) {
+			continue
+		}
+		for _, p := range split_paths(k, env[k]) {
+			if orig != "" && is_subpath(p, orig) {
+				fmt.Fprintf(w, "**warn** %s: [%s] was not relocated\n", k, p)
+				nstale++
+			}
+			switch {
+			case g_file_paths[k]:
+				if !path_exists(p) {
+					fmt.Fprintf(w, "**error** %s: [%s] does not exist\n", k, p)
+					ndangling++
+				}
+			case !dir_exists(p):
+				fmt.Fprintf(w, "**error** %s: [%s] is not an existing directory\n", k, p)
+				ndangling++
+			}
+		}
+	}
+	fmt.Fprintf(
+		w, "::: [%d] dangling path(s), [%d] path(s) not relocated\n",
+		ndangling, nstale,
+	)
+	return ndangling
+}
+
+// is_subpath returns whether p is dir or lives underneath dir
+func is_subpath(p, dir string) bool {
+	p = filepath.Clean(p)
+	return p == dir || strings.HasPrefix(p, dir+string(filepath.Separator))
+}
+
+// dir_exists returns whether name is a directory, or a symlink to one
+func dir_exists(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && fi.IsDir()
+}
+
+// path_exists returns whether name exists, following symlinks
+func path_exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
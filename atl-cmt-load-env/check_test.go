@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckEnv(t *testing.T) {
+	top, err := ioutil.TempDir("", "atl-cmt-load-env-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(top)
+
+	dir := filepath.Join(top, "dir")
+	zip := filepath.Join(top, "lib.zip")
+	missing := filepath.Join(top, "missing")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(zip, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		env  map[string]string
+		want int
+	}{
+		{map[string]string{"PATH": dir + ":" + missing}, 1},
+		{map[string]string{"LD_LIBRARY_PATH": zip}, 1},
+		{map[string]string{"PYTHONPATH": dir + ":" + zip}, 0},
+		{map[string]string{"PYTHONPATH": zip + ":" + missing}, 1},
+		{map[string]string{"JOBOPTSEARCHPATH": dir + "," + dir + ":" + dir}, 0},
+		{map[string]string{"JOBOPTSEARCHPATH": dir + "," + missing}, 1},
+		{map[string]string{"CMTCONFIG": missing}, 0},
+	} {
+		got := check_env(ioutil.Discard, test.env, "")
+		if got != test.want {
+			t.Errorf("check_env(%q): got %d dangling path(s), want %d", test.env, got, test.want)
+		}
+	}
+}
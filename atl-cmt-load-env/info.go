@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/atlas-org/scripts/cmtenv"
+)
+
+// print_list writes the names of the environments held in store into w
+func print_list(w io.Writer, store *cmtenv.Store) error {
+	for _, name := range store.Names() {
+		cache, err := store.Entry(name)
+		if err != nil {
+			return err
+		}
+		prov, ok, err := cache.Provenance()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintf(w, "%s\n", name)
+			continue
+		}
+		fmt.Fprintf(
+			w, "%-20s %-25s %-25s %s\n",
+			name, prov.Tags, prov.Platform, prov.Time.Format(time.RFC3339),
+		)
+	}
+	return nil
+}
+
+// print_info writes the provenance of the cache into w
+func print_info(w io.Writer, cache *cmtenv.Cache) error {
+	prov, ok, err := cache.Provenance()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintf(w, "::: no provenance information\n")
+		fmt.Fprintf(w, "topdir:   %s\n", cache.Topdir())
+	} else {
+		tags := prov.Tags
+		if tags == "" {
+			tags = "(environment taken from the shell)"
+		}
+		fmt.Fprintf(w, "tags:     %s\n", tags)
+		fmt.Fprintf(w, "host:     %s\n", prov.Host)
+		fmt.Fprintf(w, "platform: %s\n", prov.Platform)
+		fmt.Fprintf(w, "saved:    %s\n", prov.Time.Format(time.RFC3339))
+		fmt.Fprintf(w, "tool:     %s\n", prov.Tool)
+		fmt.Fprintf(w, "topdir:   %s\n", prov.Topdir)
+	}
+
+	filter, ok, err := cache.Filter()
+	if err != nil {
+		return err
+	}
+	if ok {
+		include := "*"
+		if len(filter.Include) > 0 {
+			include = strings.Join(filter.Include, ",")
+		}
+		fmt.Fprintf(w, "include:  %s\n", include)
+		fmt.Fprintf(w, "exclude:  %s\n", strings.Join(filter.Exclude, ","))
+		fmt.Fprintf(w, "scrub:    %v\n", filter.Scrub)
+	}
+	return nil
+}
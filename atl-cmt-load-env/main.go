@@ -5,15 +5,23 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 
-	gocmt "github.com/atlas-org/cmt"
+	"github.com/atlas-org/scripts/cmtenv"
 )
 
 var g_verbose = flag.Bool("v", false, "enable verbose output")
 var g_dir = flag.String("d", ".", "directory to relocate the environment to")
 var g_fname = flag.String("f", "store.cmt", "path to file to load the environment from")
+var g_name = flag.String("name", "", "name of the environment to load from the file")
+var g_list = flag.Bool("list", false, "list the environments held in the file")
 var g_oname = flag.String("o", "", "shell file to hold the environment")
-var g_shell = flag.String("sh", "sh", "shell type (sh|csh)")
+var g_shell = flag.String("sh", "sh", "shell type (sh|csh) or modulefile type (tcl|lua)")
+var g_conflict = flag.String("conflict", "", "comma-separated list of modules the generated modulefile conflicts with")
+var g_prefix = flag.Int("prefix", 0, "number of shared directory prefixes to define once as shell variables")
+var g_unload = flag.Bool("unload", false, "generate a script restoring the environment as it was before loading")
+var g_info = flag.Bool("info", false, "print where and how the environment was saved")
+var g_check = flag.Bool("check", false, "check all path-list entries exist after relocation instead of generating a script")
 var g_help = flag.Bool("h", false, "print help")
 
 func main() {
@@ -30,10 +38,19 @@ ex:
  $ eval %s%s -f my.setup.cmt%s
  $ %s -f my.setup.cmt -o setup.sh && source ./setup.sh
  $ %s -f my.setup.cmt -o setup.csh -sh=csh && source ./setup.csh
+ $ eval %s%s -f my.setup.cmt -unload%s
+ $ %s -f my.setup.cmt -d /data/work -check
+ $ %s -f my.setup.cmt -info
+ $ %s -f my.setup.cmt -list
+ $ %s -f my.setup.cmt -name rel1 -o setup.sh && source ./setup.sh
+ $ %s -f my.setup.cmt -sh=tcl -conflict=atlas -o modulefiles/atlas/19.0.0
+ $ %s -f my.setup.cmt -prefix=3 -o setup.sh
 
 options:
 `,
 			os.Args[0], bt, os.Args[0], bt, os.Args[0], os.Args[0],
+			bt, os.Args[0], bt, os.Args[0], os.Args[0], os.Args[0],
+			os.Args[0], os.Args[0], os.Args[0],
 		)
 		flag.PrintDefaults()
 
@@ -49,9 +66,21 @@ options:
 
 	switch *g_shell {
 	case "sh", "csh":
-		// ok
+		if *g_conflict != "" {
+			fmt.Fprintf(os.Stderr, "**error** -conflict is only supported for modulefiles (-sh=tcl|lua)\n")
+			os.Exit(1)
+		}
+	case "tcl", "lua":
+		if *g_unload {
+			fmt.Fprintf(os.Stderr, "**error** -unload is not supported for modulefiles (use 'module unload')\n")
+			os.Exit(1)
+		}
+		if *g_prefix > 0 {
+			fmt.Fprintf(os.Stderr, "**error** -prefix is not supported for modulefiles\n")
+			os.Exit(1)
+		}
 	default:
-		fmt.Fprintf(os.Stderr, "**error** invalid shell mode. got [%s]. valid ones: %v\n", *g_shell, "sh|csh")
+		fmt.Fprintf(os.Stderr, "**error** invalid shell mode. got [%s]. valid ones: %v\n", *g_shell, "sh|csh|tcl|lua")
 		flag.Usage()
 		os.Exit(1)
 	}
@@ -84,39 +113,99 @@ options:
 		}
 	}
 
-	setup, err := gocmt.NewSetupFromCache(*g_fname, *g_dir, *g_verbose)
+	store, err := cmtenv.Open(*g_fname)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** reading cache [%s]: %v\n", *g_fname, err)
+		os.Exit(1)
+	}
+
+	if *g_list {
+		err = print_list(out, store)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "**error** reading cache [%s]: %v\n", *g_fname, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cache, err := store.Entry(*g_name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** reading cache [%s]: %v\n", *g_fname, err)
+		os.Exit(1)
+	}
+
+	if *g_info {
+		err = print_info(out, cache)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "**error** reading cache [%s]: %v\n", *g_fname, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	setup, err := cache.Setup(*g_dir, *g_verbose)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "**error** opening cache [%s]: %v\n", *g_fname, err)
 		os.Exit(1)
 	}
 	defer setup.Delete()
 
-	export := map[string]string{
-		"sh":  "export",
-		"csh": "setenv",
-	}[*g_shell]
-
-	eq := map[string]string{
-		"sh":  "=",
-		"csh": " ",
-	}[*g_shell]
+	if *g_check {
+		orig := cache.Topdir()
+		if orig != "" {
+			orig = filepath.Clean(orig)
+		}
+		if orig == filepath.Clean(*g_dir) {
+			// environment was not relocated
+			orig = ""
+		}
+		if *g_verbose {
+			fmt.Fprintf(os.Stderr, "::: checking environment relocated from [%s] to [%s]...\n", orig, *g_dir)
+		}
+		if check_env(out, setup.EnvMap(), orig) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
 
-	for k, v := range setup.EnvMap() {
-		if k == "_" {
-			continue
+	if mod, ok := g_modulefiles[*g_shell]; ok {
+		src := *g_fname
+		if *g_name != "" {
+			src += "#" + *g_name
 		}
-		_, err = fmt.Fprintf(out, fmt.Sprintf("%s %s%s%q\n", export, k, eq, v))
+		err = write_modulefile(out, mod, setup.EnvMap(), src, cmtenv.SplitList(*g_conflict))
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "**error** for key=%q value=%q\n", k, v)
 			fmt.Fprintf(
-				os.Stderr, "**error** generating shell script [%s]: %v\n",
+				os.Stderr, "**error** generating modulefile [%s]: %v\n",
 				*g_fname,
 				err,
 			)
 			os.Exit(1)
 		}
+		return
 	}
 
+	sh := g_shells[*g_shell]
+	if *g_unload {
+		err = unload_env(out, sh, setup.EnvMap())
+	} else {
+		env := setup.EnvMap()
+		prefixes := find_prefixes(env, *g_prefix)
+		if *g_verbose {
+			for _, p := range prefixes {
+				fmt.Fprintf(os.Stderr, "::: %s=%s\n", p.name, p.dir)
+			}
+		}
+		err = load_env(out, sh, env, prefixes)
+	}
+	if err != nil {
+		fmt.Fprintf(
+			os.Stderr, "**error** generating shell script [%s]: %v\n",
+			*g_fname,
+			err,
+		)
+		os.Exit(1)
+	}
 }
 
 // EOF
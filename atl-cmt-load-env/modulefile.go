@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/atlas-org/scripts/cmtenv"
+)
+
+// modulefile holds the syntax of a modulefile flavour
+type modulefile struct {
+	header   string
+	comment  string
+	setenv   string // format of the command setting a variable
+	prepend  string // format of the command prepending an entry to a path-list
+	conflict string // format of the command declaring a conflicting module
+	quote    func(v string) string
+}
+
+var g_modulefiles = map[string]modulefile{
+	// Environment Modules
+	"tcl": {
+		header:   "#%Module1.0",
+		comment:  "##",
+		setenv:   "setenv %s %s\n",
+		prepend:  "prepend-path %s %s\n",
+		conflict: "conflict %s\n",
+		quote:    tcl_quote,
+	},
+	// Lmod
+	"lua": {
+		header:   "-- -*- lua -*-",
+		comment:  "--",
+		setenv:   "setenv(%s, %s)\n",
+		prepend:  "prepend_path(%s, %s)\n",
+		conflict: "conflict(%s)\n",
+		quote:    lua_quote,
+	},
+}
+
+// g_session_vars are the patterns of the variables describing the session
+// or the user the environment was saved by. A modulefile applies to every
+// user loading it, so they are left out.
+var g_session_vars = []string{
+	"HOME", "USER", "LOGNAME", "USERNAME", "MAIL",
+	"PWD", "OLDPWD", "SHLVL", "HOSTNAME", "HOST", "SHELL", "TERM",
+	"TMPDIR", "PS1", "PS2", "PROMPT_COMMAND", "HIST*",
+	"XDG_*", "SUDO_*", "TMUX*", "STY", "WINDOW", "LS_COLORS",
+}
+
+// is_session_var returns whether k describes the session or the user
+func is_session_var(k string) bool {
+	for _, pat := range g_session_vars {
+		if ok, _ := path.Match(pat, k); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// is_user_path returns whether the entry p of a path-list lies inside the
+// home directory home of the user the environment was saved by.
+func is_user_path(p, home string) bool {
+	if home == "" || home == "/" {
+		return false
+	}
+	home = filepath.Clean(home)
+	p = filepath.Clean(p)
+	return p == home || strings.HasPrefix(p, home+"/")
+}
+
+// write_modulefile writes a modulefile setting up env into w.
+// Path-list variables are prepended to, so the environment of the user
+// is extended rather than replaced.
+// The variables describing the session or the user the environment was saved
+// by, and the path-list entries inside their home directory, are left out.
+func write_modulefile(w io.Writer, mod modulefile, env map[string]string, src string, conflicts []string) error {
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err != nil {
+			return
+		}
+		_, err = fmt.Fprintf(w, format, args...)
+	}
+
+	printf("%s\n", mod.header)
+	printf("%s generated by atl-cmt-load-env from %s\n\n", mod.comment, src)
+
+	for _, name := range conflicts {
+		printf(mod.conflict, mod.quote(name))
+	}
+	if len(conflicts) > 0 {
+		printf("\n")
+	}
+
+	q := mod.quote
+	home := env["HOME"]
+	for _, k := range env_keys(env) {
+		if is_session_var(k) {
+			continue
+		}
+		v := env[k]
+		if !cmtenv.IsPathList(k) {
+			printf(mod.setenv, q(k), q(v))
+			continue
+		}
+		// prepend in reverse order to preserve the order of the entries
+		paths := cmtenv.SplitPathList(v)
+		for i := len(paths) - 1; i >= 0; i-- {
+			if is_user_path(paths[i], home) {
+				continue
+			}
+			printf(mod.prepend, q(k), q(paths[i]))
+		}
+	}
+	return err
+}
+
+var tcl_escaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	`$`, `\$`,
+	`[`, `\[`,
+	`]`, `\]`,
+	"\n", `\n`,
+)
+
+// tcl_quote returns v as a Tcl string literal
+func tcl_quote(v string) string {
+	return `"` + tcl_escaper.Replace(v) + `"`
+}
+
+var lua_escaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+)
+
+// lua_quote returns v as a Lua string literal
+func lua_quote(v string) string {
+	return `"` + lua_escaper.Replace(v) + `"`
+}
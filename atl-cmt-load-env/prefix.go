@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	g_prefix_name  = "ATL_RELBASE" // name of the variable holding the first prefix
+	g_prefix_min   = 4             // minimal number of paths a prefix must be shared by
+	g_prefix_depth = 3             // minimal number of directories in a prefix
+)
+
+// prefix is a directory shared by many values, held in a shell variable
+type prefix struct {
+	name string
+	dir  string
+}
+
+// find_prefixes returns up to n directories shared by many of the paths
+// held in env, most profitable first.
+func find_prefixes(env map[string]string, n int) []prefix {
+	paths := make([]string, 0)
+	for _, k := range env_keys(env) {
+		for _, tok := range strings.Split(env[k], string(os.PathListSeparator)) {
+			if filepath.IsAbs(tok) {
+				paths = append(paths, filepath.Clean(tok))
+			}
+		}
+	}
+
+	prefixes := make([]prefix, 0, n)
+	for len(prefixes) < n {
+		counts := make(map[string]int)
+		for _, p := range paths {
+			for dir := filepath.Dir(p); depth(dir) >= g_prefix_depth; dir = filepath.Dir(dir) {
+				counts[dir]++
+			}
+		}
+
+		name := g_prefix_name
+		if len(prefixes) > 0 {
+			name = fmt.Sprintf("%s_%d", g_prefix_name, len(prefixes)+1)
+		}
+		ref := len("${" + name + "}")
+
+		// pick the prefix saving the most characters
+		best := ""
+		score := 0
+		for dir, count := range counts {
+			if count < g_prefix_min {
+				continue
+			}
+			s := count * (len(dir) - ref)
+			if s > score || (s == score && dir < best) {
+				best = dir
+				score = s
+			}
+		}
+		if best == "" {
+			break
+		}
+
+		prefixes = append(prefixes, prefix{name: name, dir: best})
+
+		// only consider the paths not covered yet
+		o := paths[:0]
+		for _, p := range paths {
+			if !strings.HasPrefix(p, best+string(filepath.Separator)) {
+				o = append(o, p)
+			}
+		}
+		paths = o
+	}
+	return prefixes
+}
+
+// depth returns the number of directories in the absolute path dir
+func depth(dir string) int {
+	return strings.Count(strings.Trim(dir, string(filepath.Separator)), string(filepath.Separator)) + 1
+}
+
+// expand_prefixes replaces the directories of prefixes found at the
+// beginning of the path entries of v with a reference to their variable.
+func expand_prefixes(v string, prefixes []prefix) string {
+	if len(prefixes) == 0 {
+		return v
+	}
+	toks := strings.Split(v, string(os.PathListSeparator))
+	for i, tok := range toks {
+		for _, p := range prefixes {
+			if tok == p.dir || strings.HasPrefix(tok, p.dir+string(filepath.Separator)) {
+				toks[i] = "${" + p.name + "}" + tok[len(p.dir):]
+				break
+			}
+		}
+	}
+	return strings.Join(toks, string(os.PathListSeparator))
+}
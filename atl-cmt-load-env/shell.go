@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// shell holds the syntax of a shell family
+type shell struct {
+	export string // command to set an environment variable
+	eq     string // separator between name and value
+	unset  string // command to remove an environment variable
+	set    string // command to set a (non-exported) shell variable
+	save   string // command saving variable %[2]s into %[1]s unless already saved (%[3]s if undefined)
+}
+
+// Every generated command ends with a ';': the documented
+//   eval `atl-cmt-load-env ...`
+// joins all the lines of the script into a single one.
+
+var g_shells = map[string]shell{
+	"sh": {
+		export: "export", eq: "=", unset: "unset", set: "",
+		save: `[ -z "${%[1]s+x}" ] && export %[1]s="${%[2]s-%[3]s}"`,
+	},
+	"csh": {
+		export: "setenv", eq: " ", unset: "unsetenv", set: "set ",
+		save: "if ( ! $?%[1]s ) setenv %[1]s \"`printenv %[2]s || echo %[3]s`\"",
+	},
+}
+
+func (sh shell) setenv(w io.Writer, k, v string) error {
+	_, err := fmt.Fprintf(w, "%s %s%s%q;\n", sh.export, k, sh.eq, v)
+	return err
+}
+
+func (sh shell) setvar(w io.Writer, k, v string) error {
+	_, err := fmt.Fprintf(w, "%s%s=%q;\n", sh.set, k, v)
+	return err
+}
+
+func (sh shell) saveenv(w io.Writer, key, k, unset string) error {
+	_, err := fmt.Fprintf(w, sh.save+";\n", key, k, unset)
+	return err
+}
+
+func (sh shell) unsetenv(w io.Writer, k string) error {
+	_, err := fmt.Fprintf(w, "%s %s;\n", sh.unset, k)
+	return err
+}
+
+// env_keys returns the sorted list of variables to export from env.
+// The private variables of a loaded environment, captured along with it when
+// it was saved, are skipped.
+func env_keys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		if k == "_" || strings.HasPrefix(k, g_saved_prefix) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// load_env writes the commands exporting env into w.
+// The value each variable had before loading is saved into a private
+// variable so unload_env can restore it later on.
+// The directories of prefixes are defined once as shell variables, which
+// the exported values then refer to.
+func load_env(w io.Writer, sh shell, env map[string]string, prefixes []prefix) error {
+	for _, p := range prefixes {
+		err := sh.setvar(w, p.name, p.dir)
+		if err != nil {
+			return fmt.Errorf("prefix=%q: %v", p.dir, err)
+		}
+	}
+
+	for _, k := range env_keys(env) {
+		v := env[k]
+		err := save_env(w, sh, k)
+		if err == nil {
+			err = sh.setenv(w, k, expand_prefixes(v, prefixes))
+		}
+		if err != nil {
+			return fmt.Errorf("key=%q value=%q: %v", k, v, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// eval runs script the documented way, eval `...`, through sh and returns
+// the values of keys afterwards.
+func eval(t *testing.T, script string, keys []string) map[string]string {
+	cmd := "eval `cat`"
+	for _, k := range keys {
+		cmd += `; echo "` + k + `=${` + k + `-unset}"`
+	}
+	sh := exec.Command("sh", "-c", cmd)
+	sh.Stdin = strings.NewReader(script)
+	out, err := sh.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sh: %v\n%s", err, out)
+	}
+
+	o := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			t.Fatalf("sh: unexpected output %q", line)
+		}
+		o[kv[0]] = kv[1]
+	}
+	return o
+}
+
+func TestLoadUnloadEval(t *testing.T) {
+	sh := g_shells["sh"]
+	env := map[string]string{
+		"ATL_TEST_NEW": "/rel/new",
+		"ATL_TEST_OLD": "/rel/old",
+	}
+	prefixes := []prefix{{name: "ATL_TEST_REL", dir: "/rel"}}
+	keys := []string{
+		"ATL_TEST_NEW", "ATL_TEST_OLD",
+		g_saved_prefix + "ATL_TEST_NEW", g_saved_prefix + "ATL_TEST_OLD",
+	}
+
+	t.Setenv("ATL_TEST_OLD", "old")
+
+	var load bytes.Buffer
+	err := load_env(&load, sh, env, prefixes)
+	if err != nil {
+		t.Fatalf("load_env: %v", err)
+	}
+	loaded := eval(t, load.String(), keys)
+	for k, want := range map[string]string{
+		"ATL_TEST_NEW":                  "/rel/new",
+		"ATL_TEST_OLD":                  "/rel/old",
+		g_saved_prefix + "ATL_TEST_NEW": g_saved_unset,
+		g_saved_prefix + "ATL_TEST_OLD": "old",
+	} {
+		if loaded[k] != want {
+			t.Errorf("load %s: got %q, want %q", k, loaded[k], want)
+		}
+	}
+
+	// unload from the loaded environment
+	for k, v := range loaded {
+		t.Setenv(k, v)
+	}
+
+	var unload bytes.Buffer
+	err = unload_env(&unload, sh, env)
+	if err != nil {
+		t.Fatalf("unload_env: %v", err)
+	}
+	unloaded := eval(t, unload.String(), keys)
+	for k, want := range map[string]string{
+		"ATL_TEST_NEW":                  "unset",
+		"ATL_TEST_OLD":                  "old",
+		g_saved_prefix + "ATL_TEST_NEW": "unset",
+		g_saved_prefix + "ATL_TEST_OLD": "unset",
+	} {
+		if unloaded[k] != want {
+			t.Errorf("unload %s: got %q, want %q", k, unloaded[k], want)
+		}
+	}
+}
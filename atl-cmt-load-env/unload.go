@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/atlas-org/scripts/cmtenv"
+)
+
+const (
+	// g_saved_prefix prefixes the private variables holding the values
+	// variables had before a CMT environment was loaded.
+	g_saved_prefix = "_ATL_CMT_SAVED_"
+
+	// g_saved_unset marks a variable which was not defined before loading.
+	g_saved_unset = "@atl-cmt-unset@"
+)
+
+// save_env writes the command recording the value of k into its private
+// variable. The value is taken by the shell running the script, when it is
+// loaded. An already recorded value is kept so loading twice in a row still
+// restores the original environment.
+func save_env(w io.Writer, sh shell, k string) error {
+	return sh.saveenv(w, g_saved_prefix+k, k, g_saved_unset)
+}
+
+// unload_env writes the commands undoing what load_env did for env.
+// Variables get back their previous value (or are removed). Entries added
+// to path-list variables after loading are kept.
+func unload_env(w io.Writer, sh shell, env map[string]string) error {
+	for _, k := range env_keys(env) {
+		key := g_saved_prefix + k
+		old, ok := os.LookupEnv(key)
+		if !ok {
+			// not loaded from a CMT environment
+			continue
+		}
+
+		var err error
+		switch {
+		case cmtenv.IsPathList(k):
+			err = unload_pathlist(w, sh, k, env[k], old)
+		case old == g_saved_unset:
+			err = sh.unsetenv(w, k)
+		default:
+			err = sh.setenv(w, k, old)
+		}
+		if err == nil {
+			err = sh.unsetenv(w, key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unload_pathlist restores the old value of k, which loading replaced.
+func unload_pathlist(w io.Writer, sh shell, k, loaded, old string) error {
+	unset := old == g_saved_unset
+	if unset {
+		old = ""
+	}
+
+	v := unload_paths(os.Getenv(k), loaded, old)
+	if v == "" && unset {
+		return sh.unsetenv(w, k)
+	}
+	return sh.setenv(w, k, v)
+}
+
+// unload_paths rebuilds a path list from its old value and the entries of
+// the current value which were added after loading, ie. which are neither
+// part of the loaded value nor of the old one. These entries are kept in
+// front of the old ones, where they were most likely added.
+func unload_paths(current, loaded, old string) string {
+	skip := make(map[string]bool)
+	for _, p := range cmtenv.SplitPathList(loaded) {
+		skip[p] = true
+	}
+	paths := cmtenv.SplitPathList(old)
+	for _, p := range paths {
+		skip[p] = true
+	}
+
+	added := make([]string, 0)
+	for _, p := range cmtenv.SplitPathList(current) {
+		if !skip[p] {
+			added = append(added, p)
+			skip[p] = true
+		}
+	}
+	return strings.Join(append(added, paths...), string(os.PathListSeparator))
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestUnloadPaths(t *testing.T) {
+	for _, test := range []struct {
+		current, loaded, old string
+		want                 string
+	}{
+		{"/rel/bin:/usr/bin", "/rel/bin:/usr/bin", "/home/u/bin:/usr/bin", "/home/u/bin:/usr/bin"},
+		{"/opt/bin:/rel/bin:/usr/bin", "/rel/bin:/usr/bin", "/home/u/bin:/usr/bin", "/opt/bin:/home/u/bin:/usr/bin"},
+		{"/rel/bin:/usr/bin:/opt/bin", "/rel/bin:/usr/bin", "/usr/bin", "/opt/bin:/usr/bin"},
+		{"/rel/bin:/home/u/bin", "/rel/bin", "/home/u/bin", "/home/u/bin"},
+		{"/rel/lib", "/rel/lib", "", ""},
+		{"/opt/lib::/opt/lib", "/rel/lib", "", "/opt/lib"},
+	} {
+		got := unload_paths(test.current, test.loaded, test.old)
+		if got != test.want {
+			t.Errorf("unload_paths(%q, %q, %q): got %q, want %q", test.current, test.loaded, test.old, got, test.want)
+		}
+	}
+}
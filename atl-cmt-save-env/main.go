@@ -1,16 +1,27 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	gocmt "github.com/atlas-org/cmt"
+	"github.com/atlas-org/scripts/cmtenv"
 )
 
+const g_version = "atl-cmt-save-env-0.2.0"
+
 var g_verbose = flag.Bool("v", false, "enable verbose output")
 var g_fname = flag.String("f", "store.cmt", "path to file where to store the environment")
+var g_name = flag.String("name", "", "name of the environment inside the file (default: \""+cmtenv.DefaultName+"\")")
+var g_delete = flag.String("delete", "", "name of the environment to remove from the file")
+var g_pkgs = flag.Bool("pkgs", true, "record the tags of the packages of the release")
+var g_include = flag.String("include", "", "comma-separated patterns of variables to save (default: all)")
+var g_exclude = flag.String("exclude", strings.Join(cmtenv.DefaultExclude, ","), "comma-separated patterns of variables not to save")
+var g_scrub = flag.Bool("scrub", false, "mask passwords embedded in URLs of the saved values")
 var g_help = flag.Bool("h", false, "print help")
 
 func main() {
@@ -27,10 +38,14 @@ ex:
  $ %s rel1,devval
  $ %s 19.0.0
  $ %s -f my.setup.cmt 19.0.0
+ $ %s -include='ATLAS*,CMT*,*PATH' -scrub 19.0.0
+ $ %s -name rel1 rel1,devval
+ $ %s -delete rel1
 
 options:
 `,
 			os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0],
+			os.Args[0], os.Args[0], os.Args[0],
 		)
 		flag.PrintDefaults()
 
@@ -41,6 +56,36 @@ options:
 		os.Exit(1)
 	}
 
+	if *g_delete != "" {
+		err := cmtenv.Update(*g_fname, func(store *cmtenv.Store) error {
+			return store.Delete(*g_delete)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "**error** deleting environment [%s]: %v\n", *g_delete, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// fail early, before running asetup, on an invalid destination file
+	// or invalid patterns.
+	_, err := cmtenv.Open(*g_fname)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "**error** reading file [%s]: %v\n", *g_fname, err)
+		os.Exit(1)
+	}
+
+	include, err := cmtenv.SplitPatterns(*g_include)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** invalid -include: %v\n", err)
+		os.Exit(1)
+	}
+	exclude, err := cmtenv.SplitPatterns(*g_exclude)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** invalid -exclude: %v\n", err)
+		os.Exit(1)
+	}
+
 	if *g_verbose {
 		fmt.Printf("::: setting up a CMT environment...\n")
 	}
@@ -53,18 +98,52 @@ options:
 	}
 	defer setup.Delete()
 
-	if *g_verbose {
-		fmt.Printf("::: storing CMT environment into [%s]...\n", *g_fname)
+	buf := new(bytes.Buffer)
+	err = setup.Save(buf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** saving environment: %v\n", err)
+		os.Exit(1)
+	}
+
+	cache, err := cmtenv.Decode(buf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** decoding environment: %v\n", err)
+		os.Exit(1)
 	}
 
-	f, err := os.Create(*g_fname)
+	err = record_provenance(cache, tags)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "**error** opening file [%s]: %v\n", *g_fname, err)
+		fmt.Fprintf(os.Stderr, "**error** recording provenance: %v\n", err)
 		os.Exit(1)
 	}
-	defer f.Close()
 
-	err = setup.Save(f)
+	if *g_pkgs {
+		err = record_packages(cache)
+		// a shell environment does not necessarily come with CMT
+		if err != nil && (tags != "" || *g_verbose) {
+			fmt.Fprintf(os.Stderr, "**warn** could not record package tags: %v\n", err)
+		}
+	}
+
+	filter := cmtenv.Filter{
+		Include: include,
+		Exclude: exclude,
+		Scrub:   *g_scrub,
+	}
+	err = apply_filter(cache, filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** filtering environment: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *g_verbose {
+		fmt.Printf("::: storing CMT environment into [%s]...\n", *g_fname)
+	}
+
+	err = cmtenv.Update(*g_fname, func(store *cmtenv.Store) error {
+		store.Add(*g_name, cache)
+		return nil
+	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "**error** saving file [%s]: %v\n", *g_fname, err)
 		os.Exit(1)
@@ -75,4 +154,72 @@ options:
 	}
 }
 
+// record_provenance records where and how the environment was saved
+func record_provenance(cache *cmtenv.Cache, tags string) error {
+	env, err := cache.Env()
+	if err != nil {
+		return err
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+
+	topdir := cache.Topdir()
+	if topdir == "" {
+		topdir, err = os.Getwd()
+		if err != nil {
+			return err
+		}
+	}
+
+	return cache.SetProvenance(cmtenv.Provenance{
+		Tags:     tags,
+		Host:     host,
+		Platform: env["CMTCONFIG"],
+		Time:     time.Now().UTC(),
+		Tool:     g_version,
+		Topdir:   topdir,
+	})
+}
+
+// record_packages records the tags of the packages of the release
+func record_packages(cache *cmtenv.Cache) error {
+	env, err := cache.Env()
+	if err != nil {
+		return err
+	}
+
+	pkgs, err := cmtenv.ShowPackages(env)
+	if err != nil {
+		return err
+	}
+	if *g_verbose {
+		fmt.Printf("::: recorded [%d] package tags\n", len(pkgs))
+	}
+	return cache.SetPackages(pkgs)
+}
+
+// apply_filter removes the variables rejected by filter from the cache and
+// records the filter which was used.
+func apply_filter(cache *cmtenv.Cache, filter cmtenv.Filter) error {
+	env, err := cache.Env()
+	if err != nil {
+		return err
+	}
+
+	n := len(env)
+	env = filter.Apply(env)
+	if *g_verbose {
+		fmt.Printf("::: filtered out [%d] variable(s)\n", n-len(env))
+	}
+
+	err = cache.SetEnv(env)
+	if err != nil {
+		return err
+	}
+	return cache.SetFilter(filter)
+}
+
 // EOF
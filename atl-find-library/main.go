@@ -1,106 +1,316 @@
 package main
 
 import (
+	"bufio"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
+
+	"github.com/atlas-org/scripts/findlib"
 )
 
-var g_libpaths []string
+var g_finder *findlib.Finder
+var g_indexfile string
 
-func path_exists(name string) bool {
-	_, err := os.Stat(name)
-	if err == nil {
-		return true
-	}
-	if os.IsNotExist(err) {
-		return false
+var g_all = flag.Bool("all", false, "print all the locations of the libraries, in search order")
+var g_conflicts = flag.Bool("conflicts", false, "print the libraries provided by more than one directory of LD_LIBRARY_PATH")
+var g_symbol = flag.String("symbol", "", "print the libraries defining and referencing a symbol (mangled or demangled)")
+var g_component = flag.String("component", "", "print the libraries providing a Gaudi component, from the *.components and *.confdb files")
+var g_class = flag.String("class", "", "print the libraries providing the dictionary of a class, from the *.rootmap files")
+var g_python = flag.Bool("python", false, "locate python modules (Pkg.module) along PYTHONPATH instead of libraries")
+var g_jobopts = flag.Bool("jobopts", false, "locate job options along JOBOPTSEARCHPATH instead of libraries")
+var g_data = flag.Bool("data", false, "locate data files along DATAPATH instead of libraries")
+var g_check = flag.Bool("check", false, "check the libraries can be loaded on the running platform (or -cmtconfig) and print the GLIBC/GLIBCXX versions they require")
+var g_cmtconfig = flag.String("cmtconfig", "", "platform to check the libraries against with -check (e.g. x86_64-slc6-gcc49-opt)")
+var g_deps = flag.Bool("deps", false, "print the tree of dependencies of the libraries, resolved like the dynamic loader does")
+var g_realpath = flag.Bool("realpath", false, "print the locations of the libraries with all their symlinks resolved")
+var g_path = flag.String("path", "", "colon-separated list of directories to search instead of LD_LIBRARY_PATH")
+var g_env = flag.String("env", "", "search the LD_LIBRARY_PATH of a saved CMT environment (FILE or FILE#NAME) instead of the current one")
+var g_index = flag.Bool("index", false, "cache the directory listings of the search path in an on-disk index, refreshed when directories change")
+var g_indexdir = flag.String("index-dir", "", "directory holding the -index files (default: the user cache directory)")
+var g_batch = flag.Bool("batch", false, "also read names from stdin, one per line, and prefix each result with its name")
+var g_system = flag.Bool("system", false, "also search the system loader directories (/etc/ld.so.conf, ld.so cache, defaults)")
+
+// exit saves the index of the search path if -index was given, and exits
+// with code.
+func exit(code int) {
+	if g_indexfile != "" {
+		err := g_finder.SaveIndex(g_indexfile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "**warn** could not save index [%s]: %v\n", g_indexfile, err)
+		}
 	}
-	return false
+	os.Exit(code)
 }
 
-// sysLibName returns the OS-native library name from an OS-independant one
-func sysLibName(name string) string {
-	prefix := map[string]string{
-		"linux":   "lib",
-		"darwin":  "lib",
-		"windows": "",
-	}[runtime.GOOS]
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(
+			os.Stderr,
+			`$ %s [options] LIBRARY [LIBRARY...]
 
-	suffix := map[string]string{
-		"linux":   ".so",
-		"darwin":  ".dyld",
-		"windows": ".dll",
-	}[runtime.GOOS]
+ex:
+ $ %s AthenaServices
+ $ %s -deps AthenaServices
+ $ %s -check -cmtconfig x86_64-slc6-gcc49-opt AthenaServices
+ $ %s -all AthenaServices
+ $ %s -realpath z.so.1
+ $ %s 'lib*Tools*'
+ $ %s -conflicts
+ $ %s -env cmt-env.json#rel_1 AthenaServices
+ $ %s -system -path /opt/lib z
+ $ cat libs.txt | %s -index -batch
+ $ %s -symbol 'AthenaKernel::IProxyDict'
+ $ %s -component JobOptionsSvc
+ $ %s -python AthenaCommon.Include
+ $ %s -jobopts AthExHelloWorld/HelloWorldOptions.py
+ $ %s -class xAOD::Jet
 
-	if !strings.HasPrefix(name, prefix) {
-		name = prefix + name
-	}
-	if !strings.HasSuffix(name, suffix) {
-		name = name + suffix
+options:
+`,
+			os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0],
+			os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0],
+			os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0],
+			os.Args[0],
+		)
+		flag.PrintDefaults()
 	}
-	return name
-}
+	flag.Parse()
 
-func findLib(libname string) string {
-	lib := sysLibName(libname)
-	for _, path := range g_libpaths {
-		fname := filepath.Join(path, lib)
-		if path_exists(fname) {
-			return fname
+	kind := findlib.Library
+	nkinds := 0
+	for _, k := range []struct {
+		set  bool
+		kind *findlib.Kind
+	}{
+		{*g_python, findlib.Python},
+		{*g_jobopts, findlib.JobOptions},
+		{*g_data, findlib.Data},
+	} {
+		if k.set {
+			kind = k.kind
+			nkinds++
 		}
 	}
-	return ""
-}
+	if nkinds > 1 {
+		fmt.Fprintf(os.Stderr, "**error** -python, -jobopts and -data are mutually exclusive\n")
+		os.Exit(1)
+	}
+
+	paths, err := searchPaths(kind)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** %v\n", err)
+		os.Exit(1)
+	}
+	g_finder = findlib.NewFinder(paths)
+	g_finder.Kind = kind
 
-func init() {
-	g_libpaths = func() []string {
-		v := os.Getenv("LD_LIBRARY_PATH")
-		if v == "" {
-			return nil
-		}
-		o := make([]string, 0)
-		toks := strings.Split(v, string(os.PathListSeparator))
-		for _, tok := range toks {
-			if tok != "" {
-				o = append(o, tok)
+	if *g_index {
+		dir := *g_indexdir
+		if dir == "" {
+			cache, err := os.UserCacheDir()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "**error** could not locate the user cache directory: %v\n", err)
+				os.Exit(1)
 			}
+			dir = filepath.Join(cache, "atl-find-library")
 		}
-		return o
-	}()
-}
+		g_indexfile = g_finder.IndexFile(dir)
+		err = g_finder.LoadIndex(g_indexfile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "**warn** ignoring index [%s]: %v\n", g_indexfile, err)
+		}
+	}
 
-func main() {
+	if *g_conflicts {
+		if printConflicts(os.Stdout, g_finder.Conflicts()) > 0 {
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if *g_symbol != "" {
+		if printSymbolHits(os.Stdout, g_finder.FindSymbol(*g_symbol)) == 0 {
+			fmt.Fprintf(
+				os.Stderr,
+				"**error** could not locate a library defining [%s]\n",
+				*g_symbol,
+			)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if *g_component != "" || *g_class != "" {
+		allGood := true
+		for _, q := range []struct {
+			kind string
+			name string
+			find func(string) []findlib.Provider
+		}{
+			{"component", *g_component, g_finder.FindComponent},
+			{"class", *g_class, g_finder.FindClass},
+		} {
+			if q.name == "" {
+				continue
+			}
+			if printProviders(os.Stdout, q.find(q.name)) == 0 {
+				fmt.Fprintf(
+					os.Stderr,
+					"**error** could not locate a library providing %s [%s]\n",
+					q.kind, q.name,
+				)
+				allGood = false
+			}
+		}
+		if !allGood {
+			exit(1)
+		}
+		exit(0)
+	}
 
-	if len(os.Args) <= 1 {
+	if flag.NArg() < 1 && !*g_batch {
 		fmt.Fprintf(
 			os.Stderr,
 			"**error** atl-find-library takes at least one argument\nex:\n%s\n",
 			"$ atl-find-library AthenaServices",
 		)
-		os.Exit(1)
+		exit(1)
+	}
+
+	var target findlib.Platform
+	if *g_check {
+		if *g_cmtconfig != "" {
+			target, err = findlib.ParseCmtconfig(*g_cmtconfig)
+		} else {
+			target, err = findlib.HostPlatform()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "**error** %v\n", err)
+			exit(1)
+		}
 	}
 
 	allGood := true
-	libnames := append([]string{}, os.Args[1:]...)
-	for _, libname := range libnames {
-		libname = strings.Trim(libname, " \t\r\n")
-		lib := findLib(libname)
+	check := func(lib string) {
+		if !*g_check || kind != findlib.Library {
+			return
+		}
+		ok, err := printCheck(os.Stdout, lib, target)
+		if err != nil {
+			fmt.Fprintf(
+				os.Stderr,
+				"**error** could not check library [%s]: %v\n",
+				lib, err,
+			)
+			allGood = false
+			return
+		}
+		if !ok {
+			fmt.Fprintf(
+				os.Stderr,
+				"**error** library [%s] is not compatible with [%s]\n",
+				lib, target.Name,
+			)
+			allGood = false
+		}
+	}
+
+	// emit writes a location, prefixed with the name looked up in batch mode
+	emit := func(name, fname string) {
+		if *g_batch {
+			fmt.Fprintf(os.Stdout, "%s\t%s\n", name, fname)
+			return
+		}
+		fmt.Fprintf(os.Stdout, "%s\n", fname)
+	}
+
+	lookup := func(name string) {
+		name = strings.Trim(name, " \t\r\n")
+		if *g_all || (findlib.IsGlob(name) && !*g_deps) {
+			fnames := g_finder.FindAll(name)
+			if len(fnames) == 0 {
+				fmt.Fprintf(
+					os.Stderr,
+					"**error** could not locate %s [%s]\n",
+					kind.Name, name,
+				)
+				allGood = false
+			}
+			for _, fname := range fnames {
+				if *g_realpath {
+					fname = findlib.RealPath(fname)
+				}
+				emit(name, fname)
+				check(fname)
+			}
+			return
+		}
+
+		lib := g_finder.Find(name)
 		if lib == "" {
 			fmt.Fprintf(
 				os.Stderr,
-				"**error** could not locate library [%s]\n",
-				libname,
+				"**error** could not locate %s [%s]\n",
+				kind.Name, name,
+			)
+			allGood = false
+			return
+		}
+		if !*g_deps || kind != findlib.Library {
+			if *g_realpath {
+				lib = findlib.RealPath(lib)
+			}
+			emit(name, lib)
+			check(lib)
+			return
+		}
+
+		missing, err := printDepsTree(os.Stdout, lib)
+		check(lib)
+		if err != nil {
+			fmt.Fprintf(
+				os.Stderr,
+				"**error** could not read dependencies of library [%s]: %v\n",
+				lib, err,
+			)
+			allGood = false
+			return
+		}
+		if missing > 0 {
+			fmt.Fprintf(
+				os.Stderr,
+				"**error** [%d] missing dependencies for library [%s]\n",
+				missing, name,
 			)
 			allGood = false
-			continue
 		}
-		fmt.Fprintf(os.Stdout, "%s\n", lib)
+	}
+
+	for _, name := range flag.Args() {
+		lookup(name)
+	}
+	if *g_batch {
+		scan := bufio.NewScanner(os.Stdin)
+		for scan.Scan() {
+			name := strings.TrimSpace(scan.Text())
+			if name == "" || strings.HasPrefix(name, "#") {
+				continue
+			}
+			lookup(name)
+		}
+		err = scan.Err()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "**error** reading names from stdin: %v\n", err)
+			allGood = false
+		}
 	}
 
 	if !allGood {
-		os.Exit(1)
+		exit(1)
 	}
+	exit(0)
 }
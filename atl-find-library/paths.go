@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/atlas-org/scripts/cmtenv"
+	"github.com/atlas-org/scripts/findlib"
+)
+
+// searchPaths returns the directories to search for files of the given
+// kind: the explicit -path list, or the search path variable of the kind
+// (LD_LIBRARY_PATH, PYTHONPATH, ...) in the -env saved environment or in the
+// current shell. Libraries are then looked up in the system loader
+// directories if -system was given.
+func searchPaths(kind *findlib.Kind) ([]string, error) {
+	var paths []string
+	switch {
+	case *g_path != "" && *g_env != "":
+		return nil, fmt.Errorf("-path and -env are mutually exclusive")
+	case *g_path != "":
+		paths = findlib.SplitPathList(*g_path, kind.Seps)
+	case *g_env != "":
+		cache, err := cmtenv.OpenRef(*g_env)
+		if err != nil {
+			return nil, fmt.Errorf("could not open environment [%s]: %v", *g_env, err)
+		}
+		env, err := cache.Env()
+		if err != nil {
+			return nil, fmt.Errorf("could not read environment [%s]: %v", *g_env, err)
+		}
+		paths = findlib.SplitPathList(env[kind.EnvVar], kind.Seps)
+	default:
+		paths = findlib.SplitPathList(os.Getenv(kind.EnvVar), kind.Seps)
+	}
+
+	if *g_system && kind == findlib.Library {
+		paths = append(paths, findlib.SystemPaths()...)
+	}
+	return findlib.UniquePaths(paths), nil
+}
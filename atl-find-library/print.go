@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/atlas-org/scripts/findlib"
+)
+
+// printConflicts writes the conflicting libraries into w and returns their
+// number. The first location of each library is the one which wins.
+func printConflicts(w io.Writer, conflicts map[string][]string) int {
+	names := make([]string, 0, len(conflicts))
+	for name := range conflicts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\n", name)
+		for i, fname := range conflicts[name] {
+			if i == 0 {
+				fmt.Fprintf(w, "    %s (wins)\n", fname)
+				continue
+			}
+			fmt.Fprintf(w, "    %s\n", fname)
+		}
+	}
+	return len(names)
+}
+
+// printSymbolHits writes the libraries defining and referencing the
+// symbols into w and returns the number of libraries defining them.
+func printSymbolHits(w io.Writer, hits []findlib.SymbolHits) int {
+	ndefs := 0
+	fmt.Fprintf(w, "defined in:\n")
+	for _, h := range hits {
+		if len(h.Defined) == 0 {
+			continue
+		}
+		ndefs++
+		fmt.Fprintf(w, "    %s\n", h.Lib)
+		for _, sym := range h.Defined {
+			fmt.Fprintf(w, "        %s\n", sym)
+		}
+	}
+
+	fmt.Fprintf(w, "referenced by:\n")
+	for _, h := range hits {
+		if len(h.Referenced) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "    %s\n", h.Lib)
+		for _, sym := range h.Referenced {
+			fmt.Fprintf(w, "        %s\n", sym)
+		}
+	}
+	return ndefs
+}
+
+// printProviders writes the libraries providing a component or a class into
+// w and returns the number of those which could be located.
+// The first declaration is the one which wins.
+func printProviders(w io.Writer, providers []findlib.Provider) int {
+	found := 0
+	for i, p := range providers {
+		lib := p.Path
+		if lib == "" {
+			lib = p.Lib + " => **not found**"
+		} else {
+			found++
+			if *g_realpath {
+				lib = findlib.RealPath(lib)
+			}
+		}
+		if i == 0 && len(providers) > 1 {
+			lib += " (wins)"
+		}
+		fmt.Fprintf(w, "%s\n    declared in %s\n", lib, p.Decl)
+	}
+	return found
+}
+
+// printDepsTree prints the dependency tree of the library fname into w and
+// returns the number of dependencies which could not be located.
+func printDepsTree(w io.Writer, fname string) (int, error) {
+	root, err := g_finder.Deps(fname)
+	if err != nil {
+		return 0, err
+	}
+	fmt.Fprintf(w, "%s\n", root.Path)
+	printDeps(w, root, "")
+	return root.Missing(), nil
+}
+
+// printDeps prints the dependencies of d, recursively.
+// Libraries already displayed are not expanded again.
+func printDeps(w io.Writer, d *findlib.Dep, indent string) {
+	for i, dep := range d.Deps {
+		branch, sub := "├── ", "│   "
+		if i == len(d.Deps)-1 {
+			branch, sub = "└── ", "    "
+		}
+		switch {
+		case dep.Path == "":
+			fmt.Fprintf(w, "%s%s%s => **not found**\n", indent, branch, dep.Name)
+		case dep.Seen:
+			fmt.Fprintf(w, "%s%s%s => %s [...]\n", indent, branch, dep.Name, dep.Path)
+		default:
+			fmt.Fprintf(w, "%s%s%s => %s\n", indent, branch, dep.Name, dep.Path)
+			printDeps(w, dep, indent+sub)
+		}
+	}
+}
+
+// printCheck verifies that the library fname can be loaded on the platform
+// p, writes the report into w and returns whether it is compatible.
+func printCheck(w io.Writer, fname string, p findlib.Platform) (bool, error) {
+	checks, err := p.Check(fname)
+	if err != nil {
+		return false, err
+	}
+
+	ok := true
+	for _, c := range checks {
+		status := "ok"
+		switch {
+		case !c.OK:
+			status = fmt.Sprintf("**error** %s expects %s", p.Name, c.Want)
+			ok = false
+		case c.Want == "":
+			status = "not checked"
+		}
+		switch c.Name {
+		case "class", "machine", "os-abi":
+			fmt.Fprintf(w, "    %-8s %s (%s)\n", c.Name+":", c.Have, status)
+		default:
+			fmt.Fprintf(w, "    requires %s (%s)\n", c.Have, status)
+		}
+	}
+	return ok, nil
+}
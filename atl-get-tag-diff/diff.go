@@ -0,0 +1,136 @@
+package main
+
+import (
+	"path"
+	"sort"
+
+	"github.com/atlas-org/cmt"
+)
+
+// tagdiff is the difference of a package tag between 2 releases
+type tagdiff struct {
+	Package string `json:"package"`
+	Old     string `json:"old"` // tag in the old release (empty if the package was added)
+	New     string `json:"new"` // tag in the new release (empty if the package was removed)
+}
+
+// Status returns whether the package was added, removed or changed
+func (d tagdiff) Status() string {
+	switch {
+	case d.Old == "":
+		return "added"
+	case d.New == "":
+		return "removed"
+	}
+	return "changed"
+}
+
+// pair_diff returns the tag differences between the old and new releases,
+// either set up live or read from files.
+func pair_diff(old, new string, verbose bool) ([]tagdiff, error) {
+	if is_file(old) || is_file(new) {
+		return offline_diff(old, new, verbose)
+	}
+	return live_diff(old, new, false, verbose)
+}
+
+// live_diff sets up the old and new releases and returns their tag
+// differences, as computed by cmt.TagDiff.
+func live_diff(old, new string, display, verbose bool) ([]tagdiff, error) {
+	diffs, err := cmt.TagDiff(old, new, display, verbose)
+	if err != nil {
+		return nil, err
+	}
+	tags := from_cmt(diffs)
+	sort.Sort(by_package(tags))
+	return tags, nil
+}
+
+// from_cmt converts the differences returned by cmt.TagDiff.
+// Each difference holds the package as found in the old ("ref") and in the
+// new ("chk") release.
+func from_cmt(diffs []map[string]cmt.Package) []tagdiff {
+	o := make([]tagdiff, 0, len(diffs))
+	for _, diff := range diffs {
+		ref := diff["ref"]
+		chk := diff["chk"]
+		name := ref.Name
+		if name == "" {
+			name = chk.Name
+		}
+		o = append(o, tagdiff{Package: name, Old: ref.Version, New: chk.Version})
+	}
+	return o
+}
+
+type by_package []tagdiff
+
+func (p by_package) Len() int           { return len(p) }
+func (p by_package) Less(i, j int) bool { return p[i].Package < p[j].Package }
+func (p by_package) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// filter selects the relevant tag differences
+type filter struct {
+	ignore []string        // patterns of packages to ignore
+	only   map[string]bool // statuses to keep (all if empty)
+}
+
+// keep returns whether d passes the filter.
+// Patterns are matched against the full and the base names of packages.
+func (f filter) keep(d tagdiff) bool {
+	if len(f.only) > 0 && !f.only[d.Status()] {
+		return false
+	}
+	return !f.ignored(d.Package)
+}
+
+// ignored returns whether pkg matches one of the ignore patterns
+func (f filter) ignored(pkg string) bool {
+	for _, pat := range f.ignore {
+		if ok, _ := path.Match(pat, pkg); ok {
+			return true
+		}
+		if ok, _ := path.Match(pat, path.Base(pkg)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// keep_merge returns whether e passes the filter: its package is not
+// ignored and one of its sides differs from the base in a selected way.
+func (f filter) keep_merge(e merge) bool {
+	if f.ignored(e.Package) {
+		return false
+	}
+	if len(f.only) == 0 {
+		return true
+	}
+	for _, tag := range []string{e.Ours, e.Theirs} {
+		d := tagdiff{Package: e.Package, Old: e.Base, New: tag}
+		if tag != e.Base && f.only[d.Status()] {
+			return true
+		}
+	}
+	return false
+}
+
+func (f filter) apply(diffs []tagdiff) []tagdiff {
+	o := make([]tagdiff, 0, len(diffs))
+	for _, d := range diffs {
+		if f.keep(d) {
+			o = append(o, d)
+		}
+	}
+	return o
+}
+
+func (f filter) apply_merges(merges []merge) []merge {
+	o := make([]merge, 0, len(merges))
+	for _, e := range merges {
+		if f.keep_merge(e) {
+			o = append(o, e)
+		}
+	}
+	return o
+}
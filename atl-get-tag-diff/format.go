@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+var g_formats = []string{"text", "json", "csv", "markdown", "html"}
+
+// table is a list of rows to format
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t table) write(w io.Writer, format string) error {
+	switch format {
+	case "csv":
+		return t.csv(w)
+	case "markdown":
+		return t.markdown(w)
+	case "html":
+		return t.html(w)
+	default:
+		return t.text(w)
+	}
+}
+
+func (t table) text(w io.Writer) error {
+	widths := make([]int, len(t.header))
+	for _, row := range append([][]string{t.header}, t.rows...) {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+	for _, row := range append([][]string{t.header}, t.rows...) {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = fmt.Sprintf("%-*s", widths[i], cell)
+		}
+		_, err := fmt.Fprintf(w, "%s\n", strings.TrimRight(strings.Join(cells, " | "), " "))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t table) csv(w io.Writer) error {
+	enc := csv.NewWriter(w)
+	err := enc.Write(t.header)
+	if err != nil {
+		return err
+	}
+	err = enc.WriteAll(t.rows)
+	if err != nil {
+		return err
+	}
+	enc.Flush()
+	return enc.Error()
+}
+
+var md_escaper = strings.NewReplacer("|", `\|`, "\n", " ")
+
+func (t table) markdown(w io.Writer) error {
+	line := func(cells []string) error {
+		o := make([]string, len(cells))
+		for i, cell := range cells {
+			o[i] = md_escaper.Replace(cell)
+		}
+		_, err := fmt.Fprintf(w, "| %s |\n", strings.Join(o, " | "))
+		return err
+	}
+
+	err := line(t.header)
+	if err != nil {
+		return err
+	}
+	sep := make([]string, len(t.header))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	err = line(sep)
+	if err != nil {
+		return err
+	}
+	for _, row := range t.rows {
+		err = line(row)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t table) html(w io.Writer) error {
+	line := func(tag string, cells []string) error {
+		o := make([]string, len(cells))
+		for i, cell := range cells {
+			o[i] = "<" + tag + ">" + html.EscapeString(cell) + "</" + tag + ">"
+		}
+		_, err := fmt.Fprintf(w, "  <tr>%s</tr>\n", strings.Join(o, ""))
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "<table>\n")
+	if err != nil {
+		return err
+	}
+	err = line("th", t.header)
+	if err != nil {
+		return err
+	}
+	for _, row := range t.rows {
+		err = line("td", row)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "</table>\n")
+	return err
+}
+
+// write_diffs writes the tag differences into w, in the given format
+func write_diffs(w io.Writer, format string, diffs []tagdiff) error {
+	if format == "json" {
+		type jsondiff struct {
+			tagdiff
+			Status string `json:"status"`
+		}
+		o := make([]jsondiff, 0, len(diffs))
+		for _, d := range diffs {
+			o = append(o, jsondiff{d, d.Status()})
+		}
+		buf, err := json.MarshalIndent(o, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", buf)
+		return err
+	}
+
+	t := table{header: []string{"package", "old", "new", "status"}}
+	for _, d := range diffs {
+		t.rows = append(t.rows, []string{d.Package, d.Old, d.New, d.Status()})
+	}
+	return t.write(w, format)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// g_nightlies matches a range of nightlies (ex: rel_0..rel_6)
+var g_nightlies = regexp.MustCompile(`\brel_([0-6])\.\.rel_([0-6])\b`)
+
+// expand_releases expands the ranges of nightlies of the setup strings.
+// Ranges wrap around the week: rel_5..rel_1 is rel_5,rel_6,rel_0,rel_1.
+func expand_releases(args []string) []string {
+	o := make([]string, 0, len(args))
+	for _, arg := range args {
+		m := g_nightlies.FindStringSubmatchIndex(arg)
+		if m == nil {
+			o = append(o, arg)
+			continue
+		}
+		beg, _ := strconv.Atoi(arg[m[2]:m[3]])
+		end, _ := strconv.Atoi(arg[m[4]:m[5]])
+		for i := beg; ; i = (i + 1) % 7 {
+			o = append(o, arg[:m[0]]+fmt.Sprintf("rel_%d", i)+arg[m[1]:])
+			if i == end {
+				break
+			}
+		}
+	}
+	return o
+}
+
+// history holds the tags of packages across a list of releases
+type history struct {
+	releases []string
+	tags     map[string][]string // tags of each package, per release
+}
+
+// build_history reconstructs the tags of all the packages which changed
+// across releases, from the differences between consecutive releases.
+// diffs[i] holds the differences between releases[i] and releases[i+1].
+func build_history(releases []string, diffs [][]tagdiff) history {
+	n := len(releases)
+	h := history{releases: releases, tags: make(map[string][]string)}
+	known := make(map[string][]bool)
+	changed := make(map[string][]bool) // whether a package changed between i and i+1
+
+	for i, ds := range diffs {
+		for _, d := range ds {
+			if _, ok := h.tags[d.Package]; !ok {
+				h.tags[d.Package] = make([]string, n)
+				known[d.Package] = make([]bool, n)
+				changed[d.Package] = make([]bool, n)
+			}
+			h.tags[d.Package][i] = d.Old
+			h.tags[d.Package][i+1] = d.New
+			known[d.Package][i] = true
+			known[d.Package][i+1] = true
+			changed[d.Package][i] = true
+		}
+	}
+
+	// a package which did not change between 2 releases kept its tag
+	for pkg, tags := range h.tags {
+		for i := 1; i < n; i++ {
+			if !known[pkg][i] && known[pkg][i-1] && !changed[pkg][i-1] {
+				tags[i] = tags[i-1]
+				known[pkg][i] = true
+			}
+		}
+		for i := n - 2; i >= 0; i-- {
+			if !known[pkg][i] && known[pkg][i+1] && !changed[pkg][i] {
+				tags[i] = tags[i+1]
+				known[pkg][i] = true
+			}
+		}
+	}
+	return h
+}
+
+// packages returns the sorted names of the packages of the history
+func (h history) packages() []string {
+	pkgs := make([]string, 0, len(h.tags))
+	for pkg := range h.tags {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// flips returns whether the tag of pkg went back to a previous value
+func (h history) flips(pkg string) bool {
+	seen := make(map[string]bool)
+	tags := h.tags[pkg]
+	for i, tag := range tags {
+		if i > 0 && tag == tags[i-1] {
+			continue
+		}
+		if seen[tag] {
+			return true
+		}
+		seen[tag] = true
+	}
+	return false
+}
+
+// write_history writes the package x release matrix into w.
+// Tags which changed with respect to the previous release are marked with
+// a '*' (except in CSV and JSON).
+func write_history(w io.Writer, format string, h history) error {
+	if format == "json" {
+		type entry struct {
+			Package string   `json:"package"`
+			Tags    []string `json:"tags"`
+			Flip    bool     `json:"flip"`
+		}
+		doc := struct {
+			Releases []string `json:"releases"`
+			Packages []entry  `json:"packages"`
+		}{Releases: h.releases, Packages: make([]entry, 0, len(h.tags))}
+		for _, pkg := range h.packages() {
+			doc.Packages = append(doc.Packages, entry{pkg, h.tags[pkg], h.flips(pkg)})
+		}
+		buf, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", buf)
+		return err
+	}
+
+	t := table{header: append(append([]string{"package"}, h.releases...), "flip")}
+	for _, pkg := range h.packages() {
+		tags := h.tags[pkg]
+		row := []string{pkg}
+		for i, tag := range tags {
+			if tag == "" {
+				tag = "-"
+			}
+			if i > 0 && tags[i] != tags[i-1] && format != "csv" {
+				tag = "*" + tag
+			}
+			row = append(row, tag)
+		}
+		flip := ""
+		if h.flips(pkg) {
+			flip = "FLIP"
+		}
+		t.rows = append(t.rows, append(row, flip))
+	}
+	return t.write(w, format)
+}
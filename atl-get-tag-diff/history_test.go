@@ -0,0 +1,89 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandReleases(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		want []string
+	}{
+		{
+			[]string{"19.0.0", "rel_5..rel_1,devval"},
+			[]string{"19.0.0", "rel_5,devval", "rel_6,devval", "rel_0,devval", "rel_1,devval"},
+		},
+		{[]string{"rel_2..rel_2"}, []string{"rel_2"}},
+		// not nightlies: left as is
+		{[]string{"rel_7..rel_1", "rel_1..rel_9"}, []string{"rel_7..rel_1", "rel_1..rel_9"}},
+		{[]string{"rel_1..rel_23"}, []string{"rel_1..rel_23"}},
+	} {
+		got := expand_releases(test.args)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("expand_releases(%q): got %q, want %q", test.args, got, test.want)
+		}
+	}
+}
+
+func TestBuildHistory(t *testing.T) {
+	releases := []string{"rel_0", "rel_1", "rel_2", "rel_3"}
+	for _, test := range []struct {
+		name  string
+		diffs [][]tagdiff
+		want  map[string][]string
+		flips map[string]bool
+	}{
+		{
+			name: "changed once",
+			diffs: [][]tagdiff{
+				nil,
+				{{Package: "A/P", Old: "P-01", New: "P-02"}},
+				nil,
+			},
+			want: map[string][]string{"A/P": {"P-01", "P-01", "P-02", "P-02"}},
+		},
+		{
+			name: "flip",
+			diffs: [][]tagdiff{
+				{{Package: "A/P", Old: "P-01", New: "P-02"}},
+				nil,
+				{{Package: "A/P", Old: "P-02", New: "P-01"}},
+			},
+			want:  map[string][]string{"A/P": {"P-01", "P-02", "P-02", "P-01"}},
+			flips: map[string]bool{"A/P": true},
+		},
+		{
+			name: "added then removed",
+			diffs: [][]tagdiff{
+				{{Package: "A/New", New: "New-01"}},
+				{{Package: "A/New", Old: "New-01", New: "New-02"}},
+				{{Package: "A/New", Old: "New-02"}},
+			},
+			want:  map[string][]string{"A/New": {"", "New-01", "New-02", ""}},
+			flips: map[string]bool{"A/New": true},
+		},
+		{
+			name: "several packages",
+			diffs: [][]tagdiff{
+				{{Package: "A/P", Old: "P-01", New: "P-02"}},
+				{{Package: "A/Q", Old: "Q-01", New: "Q-02"}},
+				{{Package: "A/P", Old: "P-02", New: "P-03"}},
+			},
+			want: map[string][]string{
+				"A/P": {"P-01", "P-02", "P-02", "P-03"},
+				"A/Q": {"Q-01", "Q-01", "Q-02", "Q-02"},
+			},
+		},
+	} {
+		h := build_history(releases, test.diffs)
+		if !reflect.DeepEqual(h.tags, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, h.tags, test.want)
+		}
+		for _, pkg := range h.packages() {
+			if got := h.flips(pkg); got != test.flips[pkg] {
+				t.Errorf("%s: flips(%s): got %v, want %v", test.name, pkg, got, test.flips[pkg])
+			}
+		}
+	}
+}
@@ -5,53 +5,235 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
-	"github.com/atlas-org/cmt"
+	"github.com/atlas-org/scripts/cmtenv"
+)
+
+const (
+	g_exit_same  = 0 // no (relevant) tag difference
+	g_exit_diffs = 1 // releases differ
+	g_exit_error = 2 // releases could not be compared
 )
 
 func main() {
 	v := flag.Bool("v", false, "enable verbose mode")
+	format := flag.String("o", "text", "output format ("+strings.Join(g_formats, "|")+")")
+	ignore := flag.String("ignore", "", "comma-separated patterns of packages to ignore (ex: 'External/*')")
+	three := flag.Bool("merge", false, "three-way diff between base, ours and theirs releases")
+	svnlog := flag.Bool("log", false, "annotate changed packages with the SVN commits between their old and new tags (text|json only)")
+	loglen := flag.Int("log-len", 80, "maximum length of the displayed commit messages")
+	logmax := flag.Int("log-max", 20, "maximum number of commits displayed per package (0: all)")
+	njobs := flag.Int("j", 4, "number of concurrent svn queries")
+	only := flag.String("only", "", "comma-separated list of differences to consider (added|removed|changed)")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
 		fmt.Fprintf(
 			os.Stderr,
-			`$ %s [options] old-setup new-setup
+			`$ %s [options] old-release new-release
+$ %s [options] release-1 release-2 ... release-N
+$ %s [options] -merge base-release ours-release theirs-release
+
+a release is either a setup-string, a cache file written by atl-cmt-save-env
+(FILE or FILE#NAME) or a manifest file holding "package tag" lines.
 
 ex:
  $ %s rel1,devval rel2,devval
  $ %s 19.0.0 rel2,devval
+ $ %s -o markdown 19.0.0 19.0.1
+ $ %s store.cmt#rel1 store.cmt#rel2
+ $ %s 19.0.0-manifest.txt 19.0.1.cmt
+ $ %s -ignore='External/*' -only=changed rel1,devval rel2,devval
+ $ %s rel_0..rel_6,devval
+ $ %s -merge 19.0.0 19.0.X,rel_1 rel_1,devval
+ $ %s -log -log-len=60 rel1,devval rel2,devval
+
+exit status is 0 if releases have no (relevant) tag difference, 1 if they
+differ and 2 if they could not be compared.
+with -merge, exit status is 1 only if some packages are in conflict.
 
 options:
 `,
-			os.Args[0], os.Args[0], os.Args[0],
+			os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0],
+			os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0],
+			os.Args[0], os.Args[0],
 		)
 		flag.PrintDefaults()
 
 	}
 	flag.Parse()
 
-	old := ""
-	new := ""
-
-	switch flag.NArg() {
+	switch *format {
+	case "text", "json", "csv", "markdown", "html":
+		// ok
 	default:
-		fmt.Fprintf(os.Stderr, "**error** you need to give 2 releases/nightlies setup-strings\n")
+		fmt.Fprintf(os.Stderr, "**error** invalid output format [%s]. valid ones: %s\n", *format, strings.Join(g_formats, "|"))
+		flag.Usage()
+		os.Exit(g_exit_error)
+	}
+
+	if *svnlog && *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "**error** -log only supports the text and json output formats\n")
+		os.Exit(g_exit_error)
+	}
+
+	sel := filter{ignore: cmtenv.SplitList(*ignore), only: make(map[string]bool)}
+	for _, status := range cmtenv.SplitList(*only) {
+		switch status {
+		case "added", "removed", "changed":
+			sel.only[status] = true
+		default:
+			fmt.Fprintf(os.Stderr, "**error** invalid difference [%s]. valid ones: added|removed|changed\n", status)
+			flag.Usage()
+			os.Exit(g_exit_error)
+		}
+	}
+
+	releases := expand_releases(flag.Args())
+	if *three {
+		if len(releases) != 3 {
+			fmt.Fprintf(os.Stderr, "**error** you need to give 3 releases (base, ours, theirs) with -merge\n")
+			fmt.Fprintf(os.Stderr, "**error** got [%d]: %v\n", len(releases), releases)
+			flag.Usage()
+			os.Exit(g_exit_error)
+		}
+		os.Exit(run_merge(releases[0], releases[1], releases[2], *format, sel, *v))
+	}
+
+	switch len(releases) {
+	case 0, 1:
+		fmt.Fprintf(os.Stderr, "**error** you need to give at least 2 releases/nightlies setup-strings\n")
 		fmt.Fprintf(os.Stderr, "**error** got [%d]: %v\n", flag.NArg(), flag.Args())
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(g_exit_error)
 	case 2:
-		old = flag.Args()[0]
-		new = flag.Args()[1]
+		// ok
+	default:
+		os.Exit(run_history(releases, *format, sel, *v))
+	}
+
+	old := releases[0]
+	new := releases[1]
+
+	var tags []tagdiff
+	var err error
+
+	// let cmt display the differences of live releases in text mode,
+	// format them ourselves otherwise.
+	display := *format == "text" && len(sel.ignore) == 0 && len(sel.only) == 0 && !*svnlog
+	if is_file(old) || is_file(new) {
+		display = false
+		tags, err = offline_diff(old, new, *v)
+	} else {
+		tags, err = live_diff(old, new, display, *v)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** %v\n", err)
+		os.Exit(g_exit_error)
+	}
+
+	tags = sel.apply(tags)
+	switch {
+	case *svnlog:
+		logs, err := fetch_logs(tags, *logmax, *njobs)
+		if err == nil {
+			err = write_logs(os.Stdout, *format, logs, *loglen)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "**error** %v\n", err)
+			os.Exit(g_exit_error)
+		}
+	case !display:
+		err = write_diffs(os.Stdout, *format, tags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "**error** %v\n", err)
+			os.Exit(g_exit_error)
+		}
+	}
+	if len(tags) > 0 {
+		os.Exit(g_exit_diffs)
+	}
+	os.Exit(g_exit_same)
+}
+
+// run_history displays the tags history of packages across releases and
+// returns the exit status.
+func run_history(releases []string, format string, sel filter, verbose bool) int {
+	// each release is set up (or read) once, then compared to the next one
+	tags := make([]map[string]string, len(releases))
+	for i, rel := range releases {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "::: reading the tags of [%s]...\n", rel)
+		}
+		var err error
+		tags[i], err = release_tags(rel, verbose)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "**error** release [%s]: %v\n", rel, err)
+			return g_exit_error
+		}
+	}
+
+	diffs := make([][]tagdiff, len(releases)-1)
+	relevant := make(map[string]bool)
+	for i := range diffs {
+		diffs[i] = diff_tags(tags[i], tags[i+1])
+		for _, d := range sel.apply(diffs[i]) {
+			relevant[d.Package] = true
+		}
+	}
+
+	h := build_history(releases, diffs)
+	for pkg := range h.tags {
+		if !relevant[pkg] {
+			delete(h.tags, pkg)
+		}
+	}
+
+	err := write_history(os.Stdout, format, h)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** %v\n", err)
+		return g_exit_error
+	}
+	if len(h.tags) > 0 {
+		return g_exit_diffs
+	}
+	return g_exit_same
+}
+
+// run_merge displays the three-way tag differences between the base, ours
+// and theirs releases and returns the exit status.
+func run_merge(base, ours, theirs, format string, sel filter, verbose bool) int {
+	dours, err := pair_diff(base, ours, verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** %v\n", err)
+		return g_exit_error
+	}
+	dtheirs, err := pair_diff(base, theirs, verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** %v\n", err)
+		return g_exit_error
 	}
 
-	display := true
-	diffs, err := cmt.TagDiff(old, new, display, *v)
+	// classify on all the differences, so a package filtered out on one
+	// side still conflicts with the other side.
+	merges := sel.apply_merges(merge_diffs(dours, dtheirs))
+	err = write_merges(os.Stdout, format, merges)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "**error** %v\n", err)
-		os.Exit(1)
+		return g_exit_error
+	}
+
+	nconflicts := 0
+	for _, e := range merges {
+		if e.Status == "conflict" {
+			nconflicts++
+		}
+	}
+	if verbose {
+		fmt.Fprintf(os.Stderr, "::: [%d] package(s) changed, [%d] conflict(s)\n", len(merges), nconflicts)
 	}
-	if len(diffs) > 0 {
-		os.Exit(1)
+	if nconflicts > 0 {
+		return g_exit_diffs
 	}
-	os.Exit(0)
+	return g_exit_same
 }
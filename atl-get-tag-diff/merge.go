@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// merge classifies the tag of a package across a base release and 2
+// releases derived from it
+type merge struct {
+	Package string `json:"package"`
+	Base    string `json:"base"`
+	Ours    string `json:"ours"`
+	Theirs  string `json:"theirs"`
+	Status  string `json:"status"` // ours, theirs, both or conflict
+}
+
+// merge_diffs combines the differences base->ours and base->theirs.
+// Packages changed on a single side are classified as "ours" or "theirs",
+// packages changed the same way on both sides as "both" and packages
+// changed differently on both sides as "conflict".
+// Packages unchanged on both sides are not reported.
+func merge_diffs(ours, theirs []tagdiff) []merge {
+	m := make(map[string]*merge)
+	for _, d := range ours {
+		m[d.Package] = &merge{Package: d.Package, Base: d.Old, Ours: d.New, Theirs: d.Old, Status: "ours"}
+	}
+	for _, d := range theirs {
+		e, ok := m[d.Package]
+		if !ok {
+			m[d.Package] = &merge{Package: d.Package, Base: d.Old, Ours: d.Old, Theirs: d.New, Status: "theirs"}
+			continue
+		}
+		e.Theirs = d.New
+		if e.Ours == e.Theirs {
+			e.Status = "both"
+		} else {
+			e.Status = "conflict"
+		}
+	}
+
+	o := make([]merge, 0, len(m))
+	for _, e := range m {
+		o = append(o, *e)
+	}
+	sort.Sort(by_merge_package(o))
+	return o
+}
+
+type by_merge_package []merge
+
+func (p by_merge_package) Len() int           { return len(p) }
+func (p by_merge_package) Less(i, j int) bool { return p[i].Package < p[j].Package }
+func (p by_merge_package) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// write_merges writes the classified packages into w, in the given format
+func write_merges(w io.Writer, format string, merges []merge) error {
+	if format == "json" {
+		buf, err := json.MarshalIndent(merges, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", buf)
+		return err
+	}
+
+	t := table{header: []string{"package", "base", "ours", "theirs", "status"}}
+	for _, e := range merges {
+		t.rows = append(t.rows, []string{e.Package, e.Base, e.Ours, e.Theirs, e.Status})
+	}
+	return t.write(w, format)
+}
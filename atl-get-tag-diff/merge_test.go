@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeDiffs(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		ours   []tagdiff
+		theirs []tagdiff
+		sel    filter
+		want   []merge
+	}{
+		{
+			name:   "one side",
+			ours:   []tagdiff{{Package: "A/P", Old: "P-01", New: "P-02"}},
+			theirs: []tagdiff{{Package: "A/Q", Old: "Q-01", New: "Q-02"}},
+			want: []merge{
+				{Package: "A/P", Base: "P-01", Ours: "P-02", Theirs: "P-01", Status: "ours"},
+				{Package: "A/Q", Base: "Q-01", Ours: "Q-01", Theirs: "Q-02", Status: "theirs"},
+			},
+		},
+		{
+			name:   "both and conflict",
+			ours:   []tagdiff{{Package: "A/P", Old: "P-01", New: "P-02"}, {Package: "A/Q", Old: "Q-01", New: "Q-02"}},
+			theirs: []tagdiff{{Package: "A/P", Old: "P-01", New: "P-02"}, {Package: "A/Q", Old: "Q-01", New: "Q-03"}},
+			want: []merge{
+				{Package: "A/P", Base: "P-01", Ours: "P-02", Theirs: "P-02", Status: "both"},
+				{Package: "A/Q", Base: "Q-01", Ours: "Q-02", Theirs: "Q-03", Status: "conflict"},
+			},
+		},
+		{
+			name:   "added and removed",
+			ours:   []tagdiff{{Package: "A/New", New: "New-01"}},
+			theirs: []tagdiff{{Package: "A/Old", Old: "Old-01"}},
+			want: []merge{
+				{Package: "A/New", Ours: "New-01", Status: "ours"},
+				{Package: "A/Old", Base: "Old-01", Ours: "Old-01", Status: "theirs"},
+			},
+		},
+		{
+			name:   "changed against removed, only changed",
+			ours:   []tagdiff{{Package: "A/P", Old: "P-01", New: "P-02"}},
+			theirs: []tagdiff{{Package: "A/P", Old: "P-01"}},
+			sel:    filter{only: map[string]bool{"changed": true}},
+			want: []merge{
+				{Package: "A/P", Base: "P-01", Ours: "P-02", Status: "conflict"},
+			},
+		},
+		{
+			name:   "only removed",
+			ours:   []tagdiff{{Package: "A/P", Old: "P-01", New: "P-02"}, {Package: "A/Q", Old: "Q-01"}},
+			theirs: []tagdiff{{Package: "A/R", New: "R-01"}},
+			sel:    filter{only: map[string]bool{"removed": true}},
+			want: []merge{
+				{Package: "A/Q", Base: "Q-01", Theirs: "Q-01", Status: "ours"},
+			},
+		},
+		{
+			name:   "ignored",
+			ours:   []tagdiff{{Package: "External/P", Old: "P-01", New: "P-02"}, {Package: "A/Q", Old: "Q-01", New: "Q-02"}},
+			theirs: []tagdiff{{Package: "External/P", Old: "P-01", New: "P-03"}},
+			sel:    filter{ignore: []string{"External/*"}},
+			want: []merge{
+				{Package: "A/Q", Base: "Q-01", Ours: "Q-02", Theirs: "Q-01", Status: "ours"},
+			},
+		},
+	} {
+		got := test.sel.apply_merges(merge_diffs(test.ours, test.theirs))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s:\ngot  %+v\nwant %+v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/atlas-org/cmt"
+	"github.com/atlas-org/scripts/cmtenv"
+)
+
+// is_file returns whether the release arg is given as a file (a cache
+// written by atl-cmt-save-env or a release manifest) rather than as a
+// setup string.
+func is_file(arg string) bool {
+	fname, _ := cmtenv.SplitRef(arg)
+	fi, err := os.Stat(fname)
+	return err == nil && !fi.IsDir()
+}
+
+// release_tags returns the tags of the packages of a release, given as a
+// setup string, a cache file (FILE or FILE#NAME) or a manifest file.
+func release_tags(arg string, verbose bool) (map[string]string, error) {
+	if !is_file(arg) {
+		setup, err := cmt.NewSetup(arg, verbose)
+		if err != nil {
+			return nil, err
+		}
+		defer setup.Delete()
+		return cmtenv.ShowPackages(setup.EnvMap())
+	}
+
+	fname, _ := cmtenv.SplitRef(arg)
+	isjson, err := is_json(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isjson {
+		f, err := os.Open(fname)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return cmtenv.ReadManifest(f)
+	}
+
+	cache, err := cmtenv.OpenRef(arg)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, ok, err := cache.Packages()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("cache [%s] holds no package tags (re-save it with atl-cmt-save-env -pkgs)", arg)
+	}
+	return pkgs, nil
+}
+
+// is_json returns whether the file fname holds a JSON document
+func is_json(fname string) (bool, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		c, _, err := r.ReadRune()
+		if err != nil {
+			return false, nil
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return c == '{', nil
+	}
+}
+
+// diff_tags returns the differences between the old and new package tags
+func diff_tags(old, new map[string]string) []tagdiff {
+	old = full_names(old, new)
+	new = full_names(new, old)
+	diffs := make([]tagdiff, 0)
+	for pkg, vold := range old {
+		vnew := new[pkg]
+		if vold != vnew {
+			diffs = append(diffs, tagdiff{Package: pkg, Old: vold, New: vnew})
+		}
+	}
+	for pkg, vnew := range new {
+		if _, ok := old[pkg]; !ok {
+			diffs = append(diffs, tagdiff{Package: pkg, New: vnew})
+		}
+	}
+	sort.Sort(by_package(diffs))
+	return diffs
+}
+
+// full_names returns the tags of pkgs, with the packages known only by their
+// base name (as in manifests whose projects could not be found) renamed after
+// the package of ref with that base name, if there is exactly one.
+func full_names(pkgs, ref map[string]string) map[string]string {
+	bases := make(map[string][]string)
+	for pkg := range ref {
+		base := path.Base(pkg)
+		if base != pkg {
+			bases[base] = append(bases[base], pkg)
+		}
+	}
+
+	o := make(map[string]string, len(pkgs))
+	for pkg, tag := range pkgs {
+		_, known := ref[pkg]
+		if full := bases[pkg]; !known && !strings.Contains(pkg, "/") && len(full) == 1 {
+			if _, dup := pkgs[full[0]]; !dup {
+				pkg = full[0]
+			}
+		}
+		o[pkg] = tag
+	}
+	return o
+}
+
+// offline_diff computes the tag differences between 2 releases from the
+// lists of their package tags.
+func offline_diff(old, new string, verbose bool) ([]tagdiff, error) {
+	vold, err := release_tags(old, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("release [%s]: %v", old, err)
+	}
+	vnew, err := release_tags(new, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("release [%s]: %v", new, err)
+	}
+	return diff_tags(vold, vnew), nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffTags(t *testing.T) {
+	// a manifest whose projects could not be found, against a cache
+	manifest := map[string]string{
+		"AthenaKernel": "AthenaKernel-00-55-12",
+		"PyUtils":      "PyUtils-00-13-01",
+		"AtlasROOT":    "AtlasROOT-02-03-10",
+		"Gone":         "Gone-00-00-01",
+	}
+	cache := map[string]string{
+		"Control/AthenaKernel": "AthenaKernel-00-55-13",
+		"Tools/PyUtils":        "PyUtils-00-13-01",
+		"External/AtlasROOT":   "AtlasROOT-02-03-11",
+		"Tools/New":            "New-00-00-01",
+	}
+
+	got := diff_tags(manifest, cache)
+	want := []tagdiff{
+		{Package: "Control/AthenaKernel", Old: "AthenaKernel-00-55-12", New: "AthenaKernel-00-55-13"},
+		{Package: "External/AtlasROOT", Old: "AtlasROOT-02-03-10", New: "AtlasROOT-02-03-11"},
+		{Package: "Gone", Old: "Gone-00-00-01"},
+		{Package: "Tools/New", New: "New-00-00-01"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diff_tags(manifest, cache):\ngot  %v\nwant %v", got, want)
+	}
+
+	sel := filter{ignore: []string{"External/*"}, only: map[string]bool{"changed": true}}
+	got = sel.apply(diff_tags(cache, manifest))
+	want = []tagdiff{
+		{Package: "Control/AthenaKernel", Old: "AthenaKernel-00-55-13", New: "AthenaKernel-00-55-12"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("filtered diff_tags(cache, manifest):\ngot  %v\nwant %v", got, want)
+	}
+
+	// ambiguous base names are left alone
+	got = diff_tags(
+		map[string]string{"Utils": "Utils-01"},
+		map[string]string{"A/Utils": "Utils-01", "B/Utils": "Utils-01"},
+	)
+	if len(got) != 3 {
+		t.Errorf("diff_tags with an ambiguous base name: got %v, want 3 differences", got)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// logentry is a commit of the SVN history of a package
+type logentry struct {
+	Revision string `xml:"revision,attr" json:"revision"`
+	Author   string `xml:"author" json:"author"`
+	Msg      string `xml:"msg" json:"msg"`
+}
+
+// tagdiff_log is a tag difference annotated with the SVN commits between
+// the old and new tags
+type tagdiff_log struct {
+	tagdiff
+	Status string     `json:"status"`
+	Log    []logentry `json:"log"`
+	Err    string     `json:"error,omitempty"`
+}
+
+// run_svn runs svn with the given arguments and returns its output.
+// svn never prompts for credentials, as concurrent queries would interleave
+// their prompts on the terminal.
+func run_svn(args ...string) ([]byte, error) {
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd := exec.Command("svn", append([]string{"--non-interactive"}, args...)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("svn %s: %v (%s)", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// svn_tag_revision returns the revision the tag url was created at
+func svn_tag_revision(url string) (int, error) {
+	out, err := run_svn("info", "--xml", url)
+	if err != nil {
+		return 0, err
+	}
+	var info struct {
+		Entry struct {
+			Commit struct {
+				Revision int `xml:"revision,attr"`
+			} `xml:"commit"`
+		} `xml:"entry"`
+	}
+	err = xml.Unmarshal(out, &info)
+	if err != nil {
+		return 0, err
+	}
+	return info.Entry.Commit.Revision, nil
+}
+
+// svn_log returns the commits of the package between its old and new tags,
+// most recent first. The commits creating the tags are left out.
+func svn_log(svnroot string, d tagdiff, max int) ([]logentry, error) {
+	url := func(tag string) string {
+		return strings.Join([]string{svnroot, strings.TrimLeft(d.Package, "/"), "tags", tag}, "/")
+	}
+
+	oldrev, err := svn_tag_revision(url(d.Old))
+	if err != nil {
+		return nil, err
+	}
+	newrev, err := svn_tag_revision(url(d.New))
+	if err != nil {
+		return nil, err
+	}
+	if newrev-1 < oldrev+1 {
+		return []logentry{}, nil
+	}
+
+	// the new tag only exists from its creation on: its history before that
+	// is looked up through a peg revision.
+	rng := strconv.Itoa(newrev-1) + ":" + strconv.Itoa(oldrev+1)
+	args := []string{"log", "--xml", "-r", rng}
+	if max > 0 {
+		args = append(args, "-l", strconv.Itoa(max))
+	}
+	out, err := run_svn(append(args, url(d.New)+"@"+strconv.Itoa(newrev))...)
+	if err != nil {
+		return nil, err
+	}
+
+	var log struct {
+		Entries []logentry `xml:"logentry"`
+	}
+	err = xml.Unmarshal(out, &log)
+	if err != nil {
+		return nil, err
+	}
+	return log.Entries, nil
+}
+
+// fetch_logs annotates the changed packages of diffs with their SVN
+// history, running up to njobs svn queries concurrently.
+func fetch_logs(diffs []tagdiff, max, njobs int) ([]tagdiff_log, error) {
+	svnroot := os.Getenv("SVNROOT")
+	if svnroot == "" {
+		return nil, fmt.Errorf("SVNROOT not set")
+	}
+	if njobs < 1 {
+		njobs = 1
+	}
+
+	logs := make([]tagdiff_log, len(diffs))
+	throttle := make(chan struct{}, njobs)
+	done := make(chan struct{})
+	for i, d := range diffs {
+		logs[i] = tagdiff_log{tagdiff: d, Status: d.Status()}
+		go func(l *tagdiff_log) {
+			defer func() { done <- struct{}{} }()
+			if l.Status != "changed" {
+				return
+			}
+			throttle <- struct{}{}
+			defer func() { <-throttle }()
+			entries, err := svn_log(svnroot, l.tagdiff, max)
+			if err != nil {
+				l.Err = err.Error()
+				return
+			}
+			l.Log = entries
+		}(&logs[i])
+	}
+	for range diffs {
+		<-done
+	}
+	return logs, nil
+}
+
+// summary returns the first line of msg, truncated to n characters
+func summary(msg string, n int) string {
+	msg = strings.TrimSpace(msg)
+	if i := strings.Index(msg, "\n"); i >= 0 {
+		msg = strings.TrimSpace(msg[:i])
+	}
+	if n > 3 && len(msg) > n {
+		msg = msg[:n-3] + "..."
+	}
+	return msg
+}
+
+// write_logs writes the annotated tag differences into w, in the given
+// format (text or json)
+func write_logs(w io.Writer, format string, logs []tagdiff_log, n int) error {
+	if format == "json" {
+		buf, err := json.MarshalIndent(logs, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", buf)
+		return err
+	}
+
+	for _, l := range logs {
+		_, err := fmt.Fprintf(w, "%s: %s -> %s (%s)\n", l.Package, l.Old, l.New, l.Status)
+		if err != nil {
+			return err
+		}
+		if l.Err != "" {
+			fmt.Fprintf(w, "    **error** %s\n", l.Err)
+		}
+		for _, e := range l.Log {
+			fmt.Fprintf(w, "    r%s | %-10s | %s\n", e.Revision, e.Author, summary(e.Msg, n))
+		}
+	}
+	return nil
+}
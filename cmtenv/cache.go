@@ -0,0 +1,100 @@
+// Package cmtenv handles the CMT environment caches written by atl-cmt-save-env.
+//
+// A cache is the JSON document produced by gocmt.Setup.Save, annotated with
+// a few additional fields. Fields unknown to this package are preserved, so
+// annotated caches can still be loaded with gocmt.NewSetupFromCache.
+package cmtenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Cache is a CMT environment cache.
+type Cache struct {
+	doc map[string]json.RawMessage
+}
+
+// Decode reads a cache from r.
+func Decode(r io.Reader) (*Cache, error) {
+	c := &Cache{doc: make(map[string]json.RawMessage)}
+	err := json.NewDecoder(r).Decode(&c.doc)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Encode writes the cache to w.
+func (c *Cache) Encode(w io.Writer) error {
+	return json.NewEncoder(w).Encode(c.doc)
+}
+
+// key returns the name of the field name as spelled in the document,
+// matching it case-insensitively like encoding/json does.
+func (c *Cache) key(name string) string {
+	if _, ok := c.doc[name]; ok {
+		return name
+	}
+	for k := range c.doc {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return name
+}
+
+// Get decodes the field name into v.
+// Get returns false if the cache has no such field.
+func (c *Cache) Get(name string, v interface{}) (bool, error) {
+	raw, ok := c.doc[c.key(name)]
+	if !ok {
+		return false, nil
+	}
+	err := json.Unmarshal(raw, v)
+	if err != nil {
+		return true, fmt.Errorf("cmtenv: decoding field %q: %v", name, err)
+	}
+	return true, nil
+}
+
+// Set replaces the field name with the JSON encoding of v.
+func (c *Cache) Set(name string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("cmtenv: encoding field %q: %v", name, err)
+	}
+	c.doc[c.key(name)] = raw
+	return nil
+}
+
+// Env returns the environment held by the cache.
+func (c *Cache) Env() (map[string]string, error) {
+	env := make(map[string]string)
+	ok, err := c.Get("env", &env)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("cmtenv: no environment in cache")
+	}
+	return env, nil
+}
+
+// SetEnv replaces the environment held by the cache.
+func (c *Cache) SetEnv(env map[string]string) error {
+	return c.Set("env", env)
+}
+
+// Topdir returns the directory the environment was saved from.
+func (c *Cache) Topdir() string {
+	var dir string
+	c.Get("topdir", &dir)
+	if dir == "" {
+		prov, _, _ := c.Provenance()
+		dir = prov.Topdir
+	}
+	return dir
+}
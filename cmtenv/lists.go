@@ -0,0 +1,50 @@
+package cmtenv
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// IsPathList returns whether the variable k holds a list of paths
+// (PATH, LD_LIBRARY_PATH, ...).
+func IsPathList(k string) bool {
+	return strings.HasSuffix(k, "PATH")
+}
+
+// SplitPathList splits a path-list value into its non-empty entries.
+func SplitPathList(v string) []string {
+	o := make([]string, 0)
+	for _, tok := range strings.Split(v, string(os.PathListSeparator)) {
+		if tok != "" {
+			o = append(o, tok)
+		}
+	}
+	return o
+}
+
+// SplitList splits a comma-separated list of values into its non-empty,
+// trimmed values.
+func SplitList(v string) []string {
+	o := make([]string, 0)
+	for _, tok := range strings.Split(v, ",") {
+		tok = strings.TrimSpace(tok)
+		if tok != "" {
+			o = append(o, tok)
+		}
+	}
+	return o
+}
+
+// SplitPatterns splits a comma-separated list of patterns, which must follow
+// the syntax of path.Match.
+func SplitPatterns(v string) ([]string, error) {
+	o := SplitList(v)
+	for _, pat := range o {
+		if _, err := path.Match(pat, ""); err != nil {
+			return nil, fmt.Errorf("pattern [%s]: %v", pat, err)
+		}
+	}
+	return o, nil
+}
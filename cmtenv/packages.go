@@ -0,0 +1,129 @@
+package cmtenv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ShowPackages returns the tags of the packages visible from the environment
+// env, keyed by their full name (e.g. "Control/AthenaKernel"), as reported
+// by 'cmt show packages'.
+func ShowPackages(env map[string]string) (map[string]string, error) {
+	vars := make([]string, 0, len(env))
+	for k, v := range env {
+		vars = append(vars, k+"="+v)
+	}
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	// run through the shell so cmt is looked up in the PATH of env
+	cmd := exec.Command("/bin/sh", "-c", "cmt show packages")
+	cmd.Env = vars
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("cmtenv: cmt show packages: %v\n%s", err, stderr.String())
+	}
+
+	cmtpath := make([]string, 0)
+	for _, dir := range SplitPathList(env["CMTPATH"]) {
+		cmtpath = append(cmtpath, filepath.Clean(dir))
+	}
+	return parsePackages(stdout, cmtpath)
+}
+
+// ReadManifest reads a release manifest: one "package tag" pair per line,
+// packages being given by their full name, or the output of
+// 'cmt show packages'. Empty lines and lines starting with '#' are ignored.
+// The full names of the packages listed by 'cmt show packages' are derived
+// from the projects found above their paths: packages whose project cannot
+// be found keep their base name.
+func ReadManifest(r io.Reader) (map[string]string, error) {
+	return parsePackages(r, nil)
+}
+
+// parsePackages parses "name tag [path]" lines.
+// The full name of a package is made of its name and of the offset of path
+// with respect to the project (one of cmtpath, or the project directory
+// found above path if cmtpath is nil) it belongs to.
+func parsePackages(r io.Reader, cmtpath []string) (map[string]string, error) {
+	pkgs := make(map[string]string)
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		txt := strings.TrimSpace(scan.Text())
+		if txt == "" || strings.HasPrefix(txt, "#") {
+			continue
+		}
+		toks := strings.Fields(txt)
+		if len(toks) < 2 {
+			return nil, fmt.Errorf("cmtenv: invalid package line %q", txt)
+		}
+		name := toks[0]
+		if len(toks) > 2 && !strings.Contains(name, "/") {
+			projects := cmtpath
+			if projects == nil {
+				projects = findProject(toks[2])
+			}
+			name = fullName(name, toks[2], projects)
+		}
+		pkgs[name] = toks[1]
+	}
+	err := scan.Err()
+	if err != nil {
+		return nil, err
+	}
+	return pkgs, nil
+}
+
+// findProject returns the CMT project directory holding path, that is the
+// closest directory above path with a cmt/project.cmt file, if any.
+func findProject(path string) []string {
+	dir := filepath.Clean(path)
+	for {
+		_, err := os.Stat(filepath.Join(dir, "cmt", "project.cmt"))
+		if err == nil {
+			return []string{dir}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+func fullName(name, path string, cmtpath []string) string {
+	path = filepath.Clean(path)
+	for _, project := range cmtpath {
+		rel, err := filepath.Rel(project, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if rel == "." {
+			return name
+		}
+		return filepath.ToSlash(rel) + "/" + name
+	}
+	return name
+}
+
+// Packages returns the tags of the packages of the release the environment
+// was set up for.
+// Packages returns false if the cache has no such information.
+func (c *Cache) Packages() (map[string]string, bool, error) {
+	pkgs := make(map[string]string)
+	ok, err := c.Get("packages", &pkgs)
+	return pkgs, ok, err
+}
+
+// SetPackages records the tags of the packages of the release.
+func (c *Cache) SetPackages(pkgs map[string]string) error {
+	return c.Set("packages", pkgs)
+}
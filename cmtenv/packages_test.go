@@ -0,0 +1,87 @@
+package cmtenv
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	root, err := ioutil.TempDir("", "cmtenv-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	core := filepath.Join(root, "AtlasCore", "19.0.0")
+	ext := filepath.Join(root, "AtlasExternal", "19.0.0")
+	for _, project := range []string{core, ext} {
+		fname := filepath.Join(project, "cmt", "project.cmt")
+		err = os.MkdirAll(filepath.Dir(fname), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(fname, nil, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	show := fmt.Sprintf(
+		"AthenaKernel AthenaKernel-00-55-12 %[1]s/Control\n"+
+			"PyUtils PyUtils-00-13-01 %[1]s/Tools\n"+
+			"AtlasCoreRelease AtlasCoreRelease-00-00-01 %[1]s\n"+
+			"AtlasROOT AtlasROOT-02-03-10 %[2]s/External\n",
+		core, ext,
+	)
+	want := map[string]string{
+		"Control/AthenaKernel": "AthenaKernel-00-55-12",
+		"Tools/PyUtils":        "PyUtils-00-13-01",
+		"AtlasCoreRelease":     "AtlasCoreRelease-00-00-01",
+		"External/AtlasROOT":   "AtlasROOT-02-03-10",
+	}
+
+	// tags recorded in caches, from the CMTPATH of the release
+	cache, err := parsePackages(strings.NewReader(show), []string{core, ext})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cache, want) {
+		t.Errorf("cmt show packages: got %v, want %v", cache, want)
+	}
+
+	for _, manifest := range []string{
+		show,
+		"# full names\n" +
+			"Control/AthenaKernel AthenaKernel-00-55-12\n" +
+			"Tools/PyUtils PyUtils-00-13-01\n\n" +
+			"AtlasCoreRelease AtlasCoreRelease-00-00-01\n" +
+			"External/AtlasROOT AtlasROOT-02-03-10\n",
+	} {
+		got, err := ReadManifest(strings.NewReader(manifest))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, cache) {
+			t.Errorf("manifest:\n%s\ngot %v, want %v", manifest, got, cache)
+		}
+	}
+
+	// without the projects on disk, packages keep their base name
+	got, err := ReadManifest(strings.NewReader("AthenaKernel AthenaKernel-00-55-12 /no/such/AtlasCore/19.0.0/Control\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"AthenaKernel": "AthenaKernel-00-55-12"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("manifest without projects: got %v, want %v", got, want)
+	}
+
+	_, err = ReadManifest(strings.NewReader("AthenaKernel\n"))
+	if err == nil {
+		t.Errorf("manifest with a missing tag: no error")
+	}
+}
@@ -0,0 +1,28 @@
+package cmtenv
+
+import (
+	"time"
+)
+
+// Provenance describes where and how a cache was produced.
+type Provenance struct {
+	Tags     string    `json:"tags"`     // asetup tags (empty if taken from the shell)
+	Host     string    `json:"host"`     // name of the machine the environment was saved on
+	Platform string    `json:"platform"` // value of CMTCONFIG
+	Time     time.Time `json:"time"`     // time the environment was saved at
+	Tool     string    `json:"tool"`     // name and version of the tool which saved the environment
+	Topdir   string    `json:"topdir"`   // original base directory of the environment
+}
+
+// Provenance returns the provenance of the cache.
+// Provenance returns false if the cache has no such information.
+func (c *Cache) Provenance() (Provenance, bool, error) {
+	var prov Provenance
+	ok, err := c.Get("provenance", &prov)
+	return prov, ok, err
+}
+
+// SetProvenance records the provenance of the cache.
+func (c *Cache) SetProvenance(prov Provenance) error {
+	return c.Set("provenance", prov)
+}
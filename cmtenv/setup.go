@@ -0,0 +1,30 @@
+package cmtenv
+
+import (
+	"io/ioutil"
+	"os"
+
+	gocmt "github.com/atlas-org/cmt"
+)
+
+// Setup loads the environment of the cache, relocated to topdir, as
+// gocmt.NewSetupFromCache does for a plain cache file.
+func (c *Cache) Setup(topdir string, verbose bool) (*gocmt.Setup, error) {
+	f, err := ioutil.TempFile("", "atl-cmt-env-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	err = c.Encode(f)
+	if err != nil {
+		return nil, err
+	}
+	err = f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return gocmt.NewSetupFromCache(f.Name(), topdir, verbose)
+}
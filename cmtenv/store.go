@@ -0,0 +1,174 @@
+package cmtenv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// DefaultName is the name of the environment held by a plain cache file.
+const DefaultName = "default"
+
+// Store is a set of named CMT environment caches held in a single file.
+//
+// A store holding only the DefaultName environment is written as a plain
+// cache, which gocmt.NewSetupFromCache can load directly.
+type Store struct {
+	entries map[string]*Cache
+}
+
+type storeDoc struct {
+	Entries map[string]json.RawMessage `json:"entries"`
+}
+
+// NewStore returns an empty store.
+func NewStore() *Store {
+	return &Store{entries: make(map[string]*Cache)}
+}
+
+// Open reads the store held in the file fname.
+// An empty file is read as an empty store.
+func Open(fname string) (*Store, error) {
+	buf, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeStore(bytes.NewReader(buf))
+}
+
+// DecodeStore reads a store (or a plain cache) from r.
+func DecodeStore(r io.Reader) (*Store, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewStore()
+	if len(bytes.TrimSpace(buf)) == 0 {
+		return s, nil
+	}
+
+	c, err := Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := c.doc[c.key("env")]; ok {
+		s.entries[DefaultName] = c
+		return s, nil
+	}
+
+	var doc storeDoc
+	err = json.Unmarshal(buf, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Entries == nil {
+		return nil, fmt.Errorf("cmtenv: not a CMT environment cache")
+	}
+	for name, raw := range doc.Entries {
+		c, err := Decode(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("cmtenv: decoding entry %q: %v", name, err)
+		}
+		s.entries[name] = c
+	}
+	return s, nil
+}
+
+// Encode writes the store to w.
+func (s *Store) Encode(w io.Writer) error {
+	if len(s.entries) == 1 {
+		if c, ok := s.entries[DefaultName]; ok {
+			return c.Encode(w)
+		}
+	}
+
+	doc := storeDoc{Entries: make(map[string]json.RawMessage, len(s.entries))}
+	for name, c := range s.entries {
+		raw, err := json.Marshal(c.doc)
+		if err != nil {
+			return fmt.Errorf("cmtenv: encoding entry %q: %v", name, err)
+		}
+		doc.Entries[name] = raw
+	}
+	return json.NewEncoder(w).Encode(doc)
+}
+
+// Names returns the sorted names of the environments held in the store.
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Entry returns the environment named name.
+// An empty name selects the only environment of the store, or the
+// DefaultName one.
+func (s *Store) Entry(name string) (*Cache, error) {
+	if name == "" {
+		if len(s.entries) == 1 {
+			for _, c := range s.entries {
+				return c, nil
+			}
+		}
+		name = DefaultName
+		if _, ok := s.entries[name]; !ok && len(s.entries) > 1 {
+			return nil, fmt.Errorf(
+				"cmtenv: store holds several environments (%s), select one by name",
+				strings.Join(s.Names(), ", "),
+			)
+		}
+	}
+	c, ok := s.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("cmtenv: no environment named %q in store", name)
+	}
+	return c, nil
+}
+
+// Add adds the environment c to the store, replacing any environment with
+// the same name.
+func (s *Store) Add(name string, c *Cache) {
+	if name == "" {
+		name = DefaultName
+	}
+	s.entries[name] = c
+}
+
+// Delete removes the environment named name from the store.
+func (s *Store) Delete(name string) error {
+	if _, ok := s.entries[name]; !ok {
+		return fmt.Errorf("cmtenv: no environment named %q in store", name)
+	}
+	delete(s.entries, name)
+	return nil
+}
+
+// SplitRef splits a reference to an environment of the form "FILE#NAME"
+// into its file and environment names. The environment name is empty
+// when ref holds no '#'.
+func SplitRef(ref string) (fname, name string) {
+	i := strings.LastIndex(ref, "#")
+	if i < 0 {
+		return ref, ""
+	}
+	return ref[:i], ref[i+1:]
+}
+
+// OpenRef returns the environment referenced by ref (see SplitRef).
+func OpenRef(ref string) (*Cache, error) {
+	fname, name := SplitRef(ref)
+	s, err := Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	return s.Entry(name)
+}
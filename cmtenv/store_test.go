@@ -0,0 +1,115 @@
+package cmtenv
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeStore(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		input string
+		names []string
+		env   map[string]string // environment of the first entry
+	}{
+		{"empty", "", []string{}, nil},
+		{"blank", " \n", []string{}, nil},
+		{
+			"plain cache",
+			`{"env":{"FOO":"foo"},"topdir":"/work"}`,
+			[]string{DefaultName},
+			map[string]string{"FOO": "foo"},
+		},
+		{
+			"plain cache, capitalized",
+			`{"Env":{"FOO":"foo"}}`,
+			[]string{DefaultName},
+			map[string]string{"FOO": "foo"},
+		},
+		{
+			"entries",
+			`{"entries":{"dev":{"env":{"FOO":"dev"}},"prod":{"env":{"FOO":"prod"}}}}`,
+			[]string{"dev", "prod"},
+			map[string]string{"FOO": "dev"},
+		},
+	} {
+		s, err := DecodeStore(strings.NewReader(test.input))
+		if err != nil {
+			t.Errorf("%s: DecodeStore: %v", test.name, err)
+			continue
+		}
+		names := s.Names()
+		if !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%s: got names %q, want %q", test.name, names, test.names)
+			continue
+		}
+		if len(names) == 0 {
+			continue
+		}
+		c, err := s.Entry(names[0])
+		if err != nil {
+			t.Errorf("%s: Entry: %v", test.name, err)
+			continue
+		}
+		env, err := c.Env()
+		if err != nil {
+			t.Errorf("%s: Env: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(env, test.env) {
+			t.Errorf("%s: got env %q, want %q", test.name, env, test.env)
+		}
+	}
+
+	for _, input := range []string{
+		`[]`,
+		`{"topdir":"/work"}`,
+		`{"entries":{"dev":[]}}`,
+	} {
+		_, err := DecodeStore(strings.NewReader(input))
+		if err == nil {
+			t.Errorf("DecodeStore(%q): expected an error", input)
+		}
+	}
+}
+
+func TestStoreEncode(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		entries []string
+		want    string
+	}{
+		{"default", []string{DefaultName}, `{"env":{"FOO":"default"}}` + "\n"},
+		{"named", []string{"dev"}, `{"entries":{"dev":{"env":{"FOO":"dev"}}}}` + "\n"},
+		{
+			"several",
+			[]string{DefaultName, "dev"},
+			`{"entries":{"default":{"env":{"FOO":"default"}},"dev":{"env":{"FOO":"dev"}}}}` + "\n",
+		},
+	} {
+		s := NewStore()
+		for _, name := range test.entries {
+			c, err := Decode(strings.NewReader(`{}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.SetEnv(map[string]string{"FOO": name})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.Add(name, c)
+		}
+
+		buf := new(bytes.Buffer)
+		err := s.Encode(buf)
+		if err != nil {
+			t.Errorf("%s: Encode: %v", test.name, err)
+			continue
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package cmtenv
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/atlas-org/scripts/internal/fileutil"
+)
+
+// Update applies fn to the store held in fname and writes the result back.
+//
+// The store is read and written while holding an advisory lock on
+// fname+".lock", so concurrent updates do not interleave. The lock file is
+// only opened for reading, and created with the permissions the umask lets
+// through, so the users sharing a store can all lock it.
+// The new content is written to a temporary file which replaces fname only
+// once it has been checked to decode back to the same store, so fname is
+// never left empty or half-written.
+// A missing fname is handled as an empty store. A symlinked fname is
+// resolved first, so updates through the link and its target share the
+// same lock.
+func Update(fname string, fn func(s *Store) error) error {
+	if target, err := filepath.EvalSymlinks(fname); err == nil {
+		fname = target
+	}
+
+	lock, err := os.OpenFile(fname+".lock", os.O_RDONLY|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	err = lockFile(lock)
+	if err != nil {
+		return fmt.Errorf("cmtenv: locking %s: %v", fname, err)
+	}
+	defer unlockFile(lock)
+
+	s, err := Open(fname)
+	switch {
+	case os.IsNotExist(err):
+		s = NewStore()
+	case err != nil:
+		return err
+	}
+
+	err = fn(s)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	err = s.Encode(buf)
+	if err != nil {
+		return err
+	}
+	err = checkRoundTrip(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteFile(fname, buf.Bytes())
+}
+
+// checkRoundTrip verifies buf decodes into a store which encodes back to buf.
+func checkRoundTrip(buf []byte) error {
+	s, err := DecodeStore(bytes.NewReader(buf))
+	if err != nil {
+		return fmt.Errorf("cmtenv: invalid store: %v", err)
+	}
+	out := new(bytes.Buffer)
+	err = s.Encode(out)
+	if err != nil {
+		return fmt.Errorf("cmtenv: invalid store: %v", err)
+	}
+	if !bytes.Equal(buf, out.Bytes()) {
+		return fmt.Errorf("cmtenv: invalid store: content does not round-trip")
+	}
+	return nil
+}
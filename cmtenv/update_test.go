@@ -0,0 +1,104 @@
+package cmtenv
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cmtenv-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the store is shared through a symlink, which must be kept
+	fname := filepath.Join(dir, "store.cmt")
+	link := filepath.Join(dir, "link.cmt")
+	err = os.Symlink(fname, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name  string
+		fname string
+		names []string
+	}{
+		{DefaultName, fname, []string{DefaultName}},
+		{"dev", link, []string{DefaultName, "dev"}},
+		{"prod", fname, []string{DefaultName, "dev", "prod"}},
+	} {
+		err := Update(test.fname, func(s *Store) error {
+			c, err := Decode(bytes.NewReader([]byte(`{}`)))
+			if err != nil {
+				return err
+			}
+			err = c.SetEnv(map[string]string{"FOO": test.name})
+			if err != nil {
+				return err
+			}
+			s.Add(test.name, c)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: Update: %v", test.name, err)
+		}
+
+		s, err := Open(fname)
+		if err != nil {
+			t.Fatalf("%s: Open: %v", test.name, err)
+		}
+		if names := s.Names(); !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%s: got names %q, want %q", test.name, names, test.names)
+		}
+		fi, err := os.Lstat(link)
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("%s: %s is no longer a symlink (err=%v)", test.name, link, err)
+		}
+	}
+
+	// a failing update leaves the store untouched
+	want, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Update(fname, func(s *Store) error {
+		s.Delete("dev")
+		return os.ErrInvalid
+	})
+	if err != os.ErrInvalid {
+		t.Errorf("failing update: got error %v, want %v", err, os.ErrInvalid)
+	}
+	got, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("failing update: store modified:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestCheckRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		ok    bool
+	}{
+		{`{"env":{"FOO":"foo"}}` + "\n", true},
+		{`{"entries":{"dev":{"env":{"FOO":"dev"}}}}` + "\n", true},
+		{`{"entries":{}}` + "\n", true},
+		{`{"env":{"FOO":"foo"}}`, false},          // missing newline
+		{`{"env": {"FOO": "foo"}}` + "\n", false}, // not in the encoded form
+		{`{"entries":{"default":{"env":{}}}}` + "\n", false},
+		{`{"env":`, false},
+	} {
+		err := checkRoundTrip([]byte(test.input))
+		if (err == nil) != test.ok {
+			t.Errorf("checkRoundTrip(%q): got error %v, want ok=%v", test.input, err, test.ok)
+		}
+	}
+}
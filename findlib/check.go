@@ -0,0 +1,220 @@
+package findlib
+
+import (
+	"debug/elf"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Platform describes what a library must be built for to be loadable.
+type Platform struct {
+	Name    string // CMTCONFIG, or GOOS-GOARCH for the running platform
+	Class   elf.Class
+	Machine elf.Machine
+	Glibc   []int // highest GLIBC_ version provided, nil if unknown
+	Glibcxx []int // highest GLIBCXX_ version provided, nil if unknown
+}
+
+// arches maps CMTCONFIG architectures and GOARCH values to ELF classes and
+// machines.
+var arches = map[string]struct {
+	class   elf.Class
+	machine elf.Machine
+}{
+	"x86_64":  {elf.ELFCLASS64, elf.EM_X86_64},
+	"amd64":   {elf.ELFCLASS64, elf.EM_X86_64},
+	"i686":    {elf.ELFCLASS32, elf.EM_386},
+	"i386":    {elf.ELFCLASS32, elf.EM_386},
+	"386":     {elf.ELFCLASS32, elf.EM_386},
+	"aarch64": {elf.ELFCLASS64, elf.EM_AARCH64},
+	"arm64":   {elf.ELFCLASS64, elf.EM_AARCH64},
+	"arm":     {elf.ELFCLASS32, elf.EM_ARM},
+	"ppc64le": {elf.ELFCLASS64, elf.EM_PPC64},
+}
+
+// glibcs maps CMTCONFIG operating systems to the version of their glibc.
+var glibcs = map[string]string{
+	"slc5":    "2.5",
+	"slc6":    "2.12",
+	"cc7":     "2.17",
+	"centos7": "2.17",
+	"centos8": "2.28",
+	"el9":     "2.34",
+}
+
+// glibcxxs maps gcc versions to the highest GLIBCXX_ version of their
+// libstdc++.
+var glibcxxs = map[string]string{
+	"43": "3.4.10",
+	"44": "3.4.13",
+	"45": "3.4.14",
+	"46": "3.4.16",
+	"47": "3.4.17",
+	"48": "3.4.19",
+	"49": "3.4.20",
+	"5":  "3.4.21",
+	"6":  "3.4.22",
+	"7":  "3.4.24",
+	"8":  "3.4.25",
+	"9":  "3.4.28",
+	"10": "3.4.28",
+	"11": "3.4.29",
+	"12": "3.4.30",
+	"13": "3.4.32",
+	"14": "3.4.33",
+}
+
+// HostPlatform returns the running platform. Its glibc and libstdc++
+// versions are not known.
+func HostPlatform() (Platform, error) {
+	arch, ok := arches[runtime.GOARCH]
+	if !ok {
+		return Platform{}, fmt.Errorf("unsupported architecture [%s]", runtime.GOARCH)
+	}
+	return Platform{
+		Name:    runtime.GOOS + "-" + runtime.GOARCH,
+		Class:   arch.class,
+		Machine: arch.machine,
+	}, nil
+}
+
+// ParseCmtconfig returns the platform described by a CMTCONFIG value
+// (ARCH-OS-COMPILER-BUILD, e.g. x86_64-slc6-gcc49-opt).
+// The glibc and libstdc++ versions of unknown operating systems and
+// compilers are left unknown.
+func ParseCmtconfig(cfg string) (Platform, error) {
+	toks := strings.Split(cfg, "-")
+	if len(toks) != 4 {
+		return Platform{}, fmt.Errorf("invalid CMTCONFIG [%s] (expected ARCH-OS-COMPILER-BUILD)", cfg)
+	}
+	arch, ok := arches[toks[0]]
+	if !ok {
+		return Platform{}, fmt.Errorf("unsupported architecture [%s] in CMTCONFIG [%s]", toks[0], cfg)
+	}
+
+	p := Platform{
+		Name:    cfg,
+		Class:   arch.class,
+		Machine: arch.machine,
+	}
+	if v, ok := glibcs[toks[1]]; ok {
+		p.Glibc = parseVersion(v)
+	}
+	if strings.HasPrefix(toks[2], "gcc") {
+		// gcc49 is 4.9, gcc62 is 6.2 and gcc11 is 11
+		vers := toks[2][len("gcc"):]
+		n := 1
+		if strings.HasPrefix(vers, "1") || strings.HasPrefix(vers, "4") {
+			n = 2
+		}
+		if len(vers) >= n {
+			if v, ok := glibcxxs[vers[:n]]; ok {
+				p.Glibcxx = parseVersion(v)
+			}
+		}
+	}
+	return p, nil
+}
+
+// requiredVersions returns the highest version of each of the GLIBC_ and
+// GLIBCXX_ symbol versions required by the ELF file f.
+func requiredVersions(f *elf.File) (map[string][]int, error) {
+	o := make(map[string][]int)
+	syms, err := f.ImportedSymbols()
+	if err == elf.ErrNoSymbols {
+		// statically linked
+		return o, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, sym := range syms {
+		i := strings.Index(sym.Version, "_")
+		if i < 0 {
+			continue
+		}
+		prefix := sym.Version[:i+1]
+		if prefix != "GLIBC_" && prefix != "GLIBCXX_" {
+			continue
+		}
+		v := parseVersion(sym.Version[i+1:])
+		if v == nil {
+			// GLIBC_PRIVATE
+			continue
+		}
+		if lessVersion(o[prefix], v) {
+			o[prefix] = v
+		}
+	}
+	return o, nil
+}
+
+// Check is the outcome of the verification of one property of a library
+// against a platform.
+type Check struct {
+	Name string // property checked: class, machine, os-abi, GLIBC_, GLIBCXX_
+	Have string // value of the library
+	Want string // requirement of the platform, "" if it is not known
+	OK   bool   // whether the library satisfies the requirement
+}
+
+// Check verifies that the library fname can be loaded on the platform p:
+// its ELF class, machine and OS ABI, and the highest GLIBC_ and GLIBCXX_
+// symbol versions it requires.
+func (p Platform) Check(fname string) ([]Check, error) {
+	f, err := elf.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	o := []Check{
+		{
+			Name: "class",
+			Have: f.Class.String(),
+			Want: p.Class.String(),
+			OK:   f.Class == p.Class,
+		},
+		{
+			Name: "machine",
+			Have: f.Machine.String(),
+			Want: p.Machine.String(),
+			OK:   f.Machine == p.Machine,
+		},
+		{
+			Name: "os-abi",
+			Have: f.OSABI.String(),
+			Want: elf.ELFOSABI_LINUX.String(),
+			OK:   f.OSABI == elf.ELFOSABI_NONE || f.OSABI == elf.ELFOSABI_LINUX,
+		},
+	}
+
+	vers, err := requiredVersions(f)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range []struct {
+		prefix string
+		max    []int
+	}{
+		{"GLIBC_", p.Glibc},
+		{"GLIBCXX_", p.Glibcxx},
+	} {
+		req, found := vers[v.prefix]
+		if !found {
+			continue
+		}
+		c := Check{
+			Name: v.prefix,
+			Have: v.prefix + formatVersion(req),
+			OK:   true,
+		}
+		if v.max != nil {
+			c.Want = "at most " + v.prefix + formatVersion(v.max)
+			c.OK = !lessVersion(v.max, req)
+		}
+		o = append(o, c)
+	}
+	return o, nil
+}
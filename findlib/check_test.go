@@ -0,0 +1,86 @@
+package findlib
+
+import (
+	"debug/elf"
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestParseCmtconfig(t *testing.T) {
+	for _, test := range []struct {
+		cfg  string
+		want Platform
+	}{
+		{
+			"x86_64-slc6-gcc49-opt",
+			Platform{"x86_64-slc6-gcc49-opt", elf.ELFCLASS64, elf.EM_X86_64, []int{2, 12}, []int{3, 4, 20}},
+		},
+		{
+			"i686-slc5-gcc43-dbg",
+			Platform{"i686-slc5-gcc43-dbg", elf.ELFCLASS32, elf.EM_386, []int{2, 5}, []int{3, 4, 10}},
+		},
+		{
+			"x86_64-centos7-gcc62-opt",
+			Platform{"x86_64-centos7-gcc62-opt", elf.ELFCLASS64, elf.EM_X86_64, []int{2, 17}, []int{3, 4, 22}},
+		},
+		{
+			"aarch64-el9-gcc13-opt",
+			Platform{"aarch64-el9-gcc13-opt", elf.ELFCLASS64, elf.EM_AARCH64, []int{2, 34}, []int{3, 4, 32}},
+		},
+		{
+			"x86_64-mac1010-clang60-opt",
+			Platform{"x86_64-mac1010-clang60-opt", elf.ELFCLASS64, elf.EM_X86_64, nil, nil},
+		},
+	} {
+		got, err := ParseCmtconfig(test.cfg)
+		if err != nil {
+			t.Errorf("%s: %v", test.cfg, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.cfg, got, test.want)
+		}
+	}
+
+	for _, cfg := range []string{"", "x86_64-slc6", "sparc-slc6-gcc49-opt"} {
+		if _, err := ParseCmtconfig(cfg); err == nil {
+			t.Errorf("%q: expected an error", cfg)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("needs an ELF executable")
+	}
+	host, err := HostPlatform()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// the test binary itself is an ELF file built for the running platform
+	checks, err := host.Check(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range checks {
+		if !c.OK {
+			t.Errorf("%s: %s is not %s", c.Name, c.Have, c.Want)
+		}
+	}
+
+	other := host
+	other.Class = elf.ELFCLASS32
+	if other.Class == host.Class {
+		other.Class = elf.ELFCLASS64
+	}
+	checks, err = other.Check(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checks[0].Name != "class" || checks[0].OK {
+		t.Errorf("class: expected a mismatch, got %+v", checks[0])
+	}
+}
@@ -0,0 +1,185 @@
+package findlib
+
+import (
+	"debug/elf"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// elfLib holds the dynamic section of an ELF shared object.
+type elfLib struct {
+	path    string
+	class   elf.Class
+	machine elf.Machine
+	needed  []string // DT_NEEDED entries
+	rpath   []string // DT_RPATH entries, with $ORIGIN expanded
+	runpath []string // DT_RUNPATH entries, with $ORIGIN expanded
+}
+
+// openElfLib reads the dynamic section of the ELF file fname.
+func openElfLib(fname string) (*elfLib, error) {
+	f, err := elf.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lib := &elfLib{path: fname, class: f.Class, machine: f.Machine}
+	lib.needed, err = f.ImportedLibraries()
+	if err != nil {
+		return nil, err
+	}
+
+	rpath, err := f.DynString(elf.DT_RPATH)
+	if err != nil {
+		return nil, err
+	}
+	runpath, err := f.DynString(elf.DT_RUNPATH)
+	if err != nil {
+		return nil, err
+	}
+	lib.rpath = lib.expandPaths(rpath)
+	lib.runpath = lib.expandPaths(runpath)
+	return lib, nil
+}
+
+// expandPaths splits the DT_RPATH/DT_RUNPATH values and expands the
+// dynamic string tokens ($ORIGIN, $LIB) they contain.
+func (lib *elfLib) expandPaths(values []string) []string {
+	libdir := "lib"
+	if lib.class == elf.ELFCLASS64 {
+		libdir = "lib64"
+	}
+	r := strings.NewReplacer(
+		"$ORIGIN", filepath.Dir(lib.path),
+		"${ORIGIN}", filepath.Dir(lib.path),
+		"$LIB", libdir,
+		"${LIB}", libdir,
+	)
+	o := make([]string, 0)
+	for _, v := range values {
+		for _, dir := range strings.Split(v, ":") {
+			if dir != "" {
+				o = append(o, r.Replace(dir))
+			}
+		}
+	}
+	return o
+}
+
+// compatible returns whether the ELF file fname can be loaded alongside lib.
+func (lib *elfLib) compatible(fname string) bool {
+	f, err := elf.Open(fname)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	return f.Class == lib.class && f.Machine == lib.machine
+}
+
+// sysLibDirs returns the default directories searched by the dynamic loader.
+func (lib *elfLib) sysLibDirs() []string {
+	if lib.class == elf.ELFCLASS64 {
+		return []string{"/lib64", "/usr/lib64", "/lib", "/usr/lib"}
+	}
+	return []string{"/lib", "/usr/lib"}
+}
+
+// resolveDep locates the dependency name of lib the way the dynamic loader
+// does: DT_RPATH of lib and of the objects which loaded it (unless lib has a
+// DT_RUNPATH), the search path (LD_LIBRARY_PATH), DT_RUNPATH of lib, the
+// directories of the ld.so cache and configuration (ldcache), then the
+// default system directories.
+func (f *Finder) resolveDep(name string, lib *elfLib, loaders []*elfLib, ldcache []string) string {
+	if strings.Contains(name, "/") {
+		if f.exists(name) {
+			return name
+		}
+		return ""
+	}
+
+	dirs := make([]string, 0)
+	if len(lib.runpath) == 0 {
+		dirs = append(dirs, lib.rpath...)
+		for i := len(loaders) - 1; i >= 0; i-- {
+			dirs = append(dirs, loaders[i].rpath...)
+		}
+	}
+	dirs = append(dirs, f.Paths...)
+	dirs = append(dirs, lib.runpath...)
+	dirs = append(dirs, ldcache...)
+	dirs = append(dirs, lib.sysLibDirs()...)
+
+	for _, dir := range dirs {
+		fname := filepath.Join(dir, name)
+		if f.exists(fname) && lib.compatible(fname) {
+			return fname
+		}
+	}
+	return ""
+}
+
+// Dep is a node of the dependency tree of a library.
+type Dep struct {
+	Name string // DT_NEEDED entry, or the file name for the root
+	Path string // location of the library, "" if not found
+	Seen bool   // whether the library was already expanded in the tree
+	Deps []*Dep // dependencies of the library, in DT_NEEDED order
+}
+
+// Missing returns the number of dependencies of the tree d which could not
+// be located.
+func (d *Dep) Missing() int {
+	n := 0
+	if d.Path == "" {
+		n++
+	}
+	for _, dep := range d.Deps {
+		n += dep.Missing()
+	}
+	return n
+}
+
+// Deps returns the dependency tree of the library fname, resolved the way
+// the dynamic loader would, without executing anything (so it also works for
+// libraries built for another platform).
+// Libraries are only expanded at their first appearance in the tree.
+func (f *Finder) Deps(fname string) (*Dep, error) {
+	lib, err := openElfLib(fname)
+	if err != nil {
+		return nil, err
+	}
+	root := &Dep{Name: fname, Path: fname}
+	seen := map[string]bool{fname: true}
+	ldcache := LdSoCache(LdSoCacheFile)
+	ldcache = UniquePaths(append(ldcache, LdSoConf(LdSoConfFile)...))
+	err = f.deps(root, lib, nil, seen, ldcache)
+	return root, err
+}
+
+// deps fills the dependencies of the node d of library lib, recursively.
+func (f *Finder) deps(d *Dep, lib *elfLib, loaders []*elfLib, seen map[string]bool, ldcache []string) error {
+	for _, name := range lib.needed {
+		dep := &Dep{Name: name, Path: f.resolveDep(name, lib, loaders, ldcache)}
+		d.Deps = append(d.Deps, dep)
+		if dep.Path == "" {
+			continue
+		}
+		if seen[dep.Path] {
+			dep.Seen = true
+			continue
+		}
+		seen[dep.Path] = true
+
+		sub, err := openElfLib(dep.Path)
+		if err != nil {
+			return fmt.Errorf("reading [%s]: %v", dep.Path, err)
+		}
+		err = f.deps(dep, sub, append(loaders, lib), seen, ldcache)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package findlib
+
+import (
+	"debug/elf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDeps(t *testing.T) {
+	// the test binary is an ELF file of the running platform, linked
+	// statically or against the system libraries
+	d, err := NewFinder(nil).Deps(os.Args[0])
+	if err != nil {
+		t.Fatalf("Deps(%s): %v", os.Args[0], err)
+	}
+	if d.Path != os.Args[0] {
+		t.Errorf("Deps(%s): got root %q", os.Args[0], d.Path)
+	}
+	if n := d.Missing(); n != 0 {
+		t.Errorf("Deps(%s): %d missing dependencies", os.Args[0], n)
+	}
+	for _, dep := range d.Deps {
+		if _, err := os.Stat(dep.Path); err != nil {
+			t.Errorf("Deps(%s): dependency %s: %v", os.Args[0], dep.Name, err)
+		}
+	}
+
+	_, err = NewFinder(nil).Deps("deps_test.go")
+	if err == nil {
+		t.Errorf("Deps(deps_test.go): expected an error")
+	}
+}
+
+func TestExpandPaths(t *testing.T) {
+	values := []string{"$ORIGIN/../lib:${ORIGIN}/x", "/usr/$LIB::/a/${LIB}"}
+	for _, test := range []struct {
+		class elf.Class
+		want  []string
+	}{
+		{elf.ELFCLASS64, []string{"/opt/lib/../lib", "/opt/lib/x", "/usr/lib64", "/a/lib64"}},
+		{elf.ELFCLASS32, []string{"/opt/lib/../lib", "/opt/lib/x", "/usr/lib", "/a/lib"}},
+	} {
+		lib := &elfLib{path: "/opt/lib/libfoo.so", class: test.class}
+		got := lib.expandPaths(values)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("expandPaths(%v): got %q, want %q", test.class, got, test.want)
+		}
+	}
+}
+
+func TestResolveDep(t *testing.T) {
+	top, err := ioutil.TempDir("", "findlib-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(top)
+
+	exe, err := openElfLib(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each directory holds a compatible libdep.so, but "bad" which holds a
+	// file which is not an ELF one
+	dir := func(name string) string { return filepath.Join(top, name) }
+	for _, name := range []string{"rpath", "loader", "path", "runpath", "cache", "bad"} {
+		err := os.Mkdir(dir(name), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name == "bad" {
+			err = ioutil.WriteFile(filepath.Join(dir(name), "libdep.so"), []byte("!<arch>\n"), 0644)
+		} else {
+			err = os.Symlink(os.Args[0], filepath.Join(dir(name), "libdep.so"))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	newLib := func(rpath, runpath []string) *elfLib {
+		return &elfLib{
+			path:    filepath.Join(top, "libfoo.so"),
+			class:   exe.class,
+			machine: exe.machine,
+			rpath:   rpath,
+			runpath: runpath,
+		}
+	}
+	dirs := func(names ...string) []string {
+		o := make([]string, 0, len(names))
+		for _, name := range names {
+			o = append(o, dir(name))
+		}
+		return o
+	}
+
+	for _, test := range []struct {
+		name    string
+		lib     *elfLib
+		loaders []*elfLib
+		paths   []string
+		ldcache []string
+		want    string
+	}{
+		{
+			"rpath before the search path",
+			newLib(dirs("rpath"), nil), nil, dirs("path"), dirs("cache"),
+			"rpath",
+		},
+		{
+			"rpath of loaders before the search path",
+			newLib(nil, nil), []*elfLib{newLib(dirs("loader"), nil)}, dirs("path"), nil,
+			"loader",
+		},
+		{
+			"own rpath before the one of loaders",
+			newLib(dirs("rpath"), nil), []*elfLib{newLib(dirs("loader"), nil)}, nil, nil,
+			"rpath",
+		},
+		{
+			"runpath disables rpath",
+			newLib(dirs("rpath"), dirs("runpath")), []*elfLib{newLib(dirs("loader"), nil)}, dirs("path"), nil,
+			"path",
+		},
+		{
+			"runpath after the search path",
+			newLib(nil, dirs("runpath")), nil, dirs("bad"), dirs("cache"),
+			"runpath",
+		},
+		{
+			"ld.so cache last",
+			newLib(nil, nil), nil, dirs("bad"), dirs("cache"),
+			"cache",
+		},
+		{
+			"incompatible files are skipped",
+			newLib(dirs("bad"), nil), nil, dirs("bad"), nil,
+			"",
+		},
+	} {
+		f := NewFinder(test.paths)
+		got := f.resolveDep("libdep.so", test.lib, test.loaders, test.ldcache)
+		want := ""
+		if test.want != "" {
+			want = filepath.Join(dir(test.want), "libdep.so")
+		}
+		if got != want {
+			t.Errorf("%s: got %q, want %q", test.name, got, want)
+		}
+	}
+
+	// names holding a '/' are taken as is
+	f := NewFinder(dirs("path"))
+	lib := newLib(nil, nil)
+	fname := filepath.Join(dir("cache"), "libdep.so")
+	if got := f.resolveDep(fname, lib, nil, nil); got != fname {
+		t.Errorf("resolveDep(%s): got %q", fname, got)
+	}
+	fname = filepath.Join(dir("cache"), "libmissing.so")
+	if got := f.resolveDep(fname, lib, nil, nil); got != "" {
+		t.Errorf("resolveDep(%s): got %q, want \"\"", fname, got)
+	}
+}
@@ -0,0 +1,266 @@
+// Package findlib locates libraries, python modules, job options and data
+// files along search paths, the way the dynamic loader, python and athena
+// do.
+//
+// A Finder searches a list of directories for one kind of files. Directory
+// listings are read once and cached, so many lookups cost a single scan of
+// the search path. The listings can be saved into an on-disk index, which
+// later Finders load instead of scanning the directories again (see
+// LoadIndex).
+package findlib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Finder locates files of a given kind along a list of directories.
+type Finder struct {
+	Paths  []string // directories to search, in order
+	Naming Naming   // library naming rules
+	Kind   *Kind    // kind of files to locate (Library if nil)
+
+	mu      sync.Mutex
+	dirs    map[string]*listing // cached directory listings
+	dirty   bool                // whether listings were read since LoadIndex
+	indexed bool                // whether listings are read to be saved in an index
+}
+
+// listing is the cached content of a directory.
+type listing struct {
+	mtime int64                  // modification time of the directory, 0 if missing
+	names map[string]os.FileMode // type bits of the entries
+	links map[string]bool        // whether a symlink entry resolves
+}
+
+// NewFinder returns a Finder locating libraries along paths, with the naming
+// rules of the running platform.
+func NewFinder(paths []string) *Finder {
+	return &Finder{
+		Paths:  paths,
+		Naming: NativeNaming(),
+		Kind:   Library,
+	}
+}
+
+// kind returns the kind of files located by f.
+func (f *Finder) kind() *Kind {
+	if f.Kind == nil {
+		return Library
+	}
+	return f.Kind
+}
+
+// Reset drops the cached directory listings.
+func (f *Finder) Reset() {
+	f.mu.Lock()
+	f.dirs = nil
+	f.mu.Unlock()
+}
+
+// list returns the cached listing of dir, reading it if needed.
+// Unreadable directories are cached as empty.
+func (f *Finder) list(dir string) *listing {
+	dir = filepath.Clean(dir)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if l, ok := f.dirs[dir]; ok {
+		return l
+	}
+
+	l := &listing{
+		names: make(map[string]os.FileMode),
+		links: make(map[string]bool),
+	}
+	// the modification time is taken first, so entries added while the
+	// directory is read invalidate the listing
+	if fi, err := os.Stat(dir); err == nil {
+		l.mtime = fi.ModTime().UnixNano()
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err == nil {
+		for _, fi := range files {
+			l.names[fi.Name()] = fi.Mode() & os.ModeType
+		}
+	}
+	f.cache(dir, l)
+	f.dirty = true
+	return l
+}
+
+// cache stores the listing l of dir. f.mu must be held.
+func (f *Finder) cache(dir string, l *listing) {
+	if f.dirs == nil {
+		f.dirs = make(map[string]*listing)
+	}
+	f.dirs[dir] = l
+}
+
+// names returns the sorted names of the entries of dir.
+func (f *Finder) names(dir string) []string {
+	l := f.list(dir)
+	o := make([]string, 0, len(l.names))
+	for name := range l.names {
+		o = append(o, name)
+	}
+	sort.Strings(o)
+	return o
+}
+
+// cached returns the listing of dir, if it is cached or if listings are
+// read for an index. Otherwise, single files are checked with a stat rather
+// than a scan of the whole directory.
+func (f *Finder) cached(dir string) *listing {
+	f.mu.Lock()
+	l, ok := f.dirs[filepath.Clean(dir)]
+	indexed := f.indexed
+	f.mu.Unlock()
+	if ok {
+		return l
+	}
+	if indexed {
+		return f.list(dir)
+	}
+	return nil
+}
+
+// exists returns whether the file fname exists. Dangling symlinks do not.
+func (f *Finder) exists(fname string) bool {
+	dir, name := filepath.Split(filepath.Clean(fname))
+	l := f.cached(dir)
+	if l == nil {
+		_, err := os.Stat(fname)
+		return err == nil
+	}
+	mode, ok := l.names[name]
+	if !ok {
+		return false
+	}
+	if mode&os.ModeSymlink == 0 {
+		return true
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if ok, found := l.links[name]; found {
+		return ok
+	}
+	_, err := os.Stat(fname)
+	l.links[name] = err == nil
+	return err == nil
+}
+
+// isDir returns whether fname is a directory, or a symlink to one.
+func (f *Finder) isDir(fname string) bool {
+	dir, name := filepath.Split(filepath.Clean(fname))
+	l := f.cached(dir)
+	if l == nil {
+		fi, err := os.Stat(fname)
+		return err == nil && fi.IsDir()
+	}
+	mode, ok := l.names[name]
+	switch {
+	case !ok:
+		return false
+	case mode&os.ModeSymlink != 0:
+		fi, err := os.Stat(fname)
+		return err == nil && fi.IsDir()
+	}
+	return mode.IsDir()
+}
+
+// LookupFile returns dir/name if it exists, from the cached listing of dir.
+func (f *Finder) LookupFile(dir, name string) string {
+	fname := filepath.Join(dir, name)
+	if f.exists(fname) {
+		return fname
+	}
+	return ""
+}
+
+// Find returns the first location of name along the search path, or "".
+// Absolute names are returned as is, if they exist.
+// For libraries, shell-style patterns return their first match.
+func (f *Finder) Find(name string) string {
+	if f.kind() == Library && IsGlob(name) {
+		libs := f.Glob(name)
+		if len(libs) == 0 {
+			return ""
+		}
+		return libs[0]
+	}
+	if filepath.IsAbs(name) {
+		if f.exists(name) {
+			return name
+		}
+		return ""
+	}
+	o := f.lookup(f.kind(), name, true)
+	if len(o) == 0 {
+		return ""
+	}
+	return o[0]
+}
+
+// FindAll returns all the locations of name along the search path, in
+// search order: the first one is the one Find returns.
+// For libraries, shell-style patterns return all their matches.
+func (f *Finder) FindAll(name string) []string {
+	if f.kind() == Library && IsGlob(name) {
+		return f.Glob(name)
+	}
+	o := make([]string, 0)
+	if filepath.IsAbs(name) {
+		if f.exists(name) {
+			o = append(o, name)
+		}
+		return o
+	}
+	return f.lookup(f.kind(), name, false)
+}
+
+// lookup returns the locations of name of kind k in the directories of the
+// search path: the ones found by the Lookup of k first, then the ones found
+// by its Fallback in the other directories. Only the first location is
+// returned if first is set.
+func (f *Finder) lookup(k *Kind, name string, first bool) []string {
+	o := make([]string, 0)
+	found := make(map[string]bool)
+	for _, lookup := range []func(f *Finder, dir, name string) string{k.lookup, k.Fallback} {
+		if lookup == nil {
+			continue
+		}
+		for _, dir := range f.Paths {
+			if found[dir] {
+				continue
+			}
+			if fname := lookup(f, dir, name); fname != "" {
+				o = append(o, fname)
+				if first {
+					return o
+				}
+				found[dir] = true
+			}
+		}
+	}
+	return o
+}
+
+// IsGlob returns whether name is a shell-style pattern.
+func IsGlob(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// RealPath returns fname with all its symlinks resolved, or fname itself if
+// they cannot be.
+func RealPath(fname string) string {
+	real, err := filepath.EvalSymlinks(fname)
+	if err != nil {
+		return fname
+	}
+	return real
+}
@@ -0,0 +1,280 @@
+package findlib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mktree creates a temporary directory tree holding the given files,
+// relative to its root. Entries of the form "name -> target" are created as
+// symlinks.
+func mktree(t *testing.T, files ...string) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "findlib-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range files {
+		name, target := entry, ""
+		if i := strings.Index(entry, " -> "); i >= 0 {
+			name, target = entry[:i], entry[i+len(" -> "):]
+		}
+		fname := filepath.Join(root, name)
+		err = os.MkdirAll(filepath.Dir(fname), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target != "" {
+			err = os.Symlink(target, fname)
+		} else {
+			err = ioutil.WriteFile(fname, nil, 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// rel returns the names of fnames relative to root.
+func rel(root string, fnames []string) []string {
+	o := make([]string, len(fnames))
+	for i, fname := range fnames {
+		o[i] = strings.TrimPrefix(fname, root+string(os.PathSeparator))
+	}
+	return o
+}
+
+func newLinuxFinder(root string, dirs ...string) *Finder {
+	paths := make([]string, len(dirs))
+	for i, dir := range dirs {
+		paths[i] = filepath.Join(root, dir)
+	}
+	f := NewFinder(paths)
+	f.Naming = PlatformNaming("linux")
+	return f
+}
+
+func TestFind(t *testing.T) {
+	root := mktree(t,
+		"a/libFoo.so",
+		"b/libFoo.so",
+		"b/libBar.so",
+		"b/libz.so.1.2.3",
+		"b/libz.so.1.10.0",
+		"b/libz.so.1 -> libz.so.1.10.0",
+		"b/libDangling.so.2 -> nowhere",
+		"a/libv.so.2",
+		"b/libv.so",
+	)
+	defer os.RemoveAll(root)
+
+	f := newLinuxFinder(root, "a", "missing", "b")
+	for _, test := range []struct {
+		name string
+		want string
+	}{
+		{"Foo", "a/libFoo.so"},
+		{"libFoo", "a/libFoo.so"},
+		{"libFoo.so", "a/libFoo.so"},
+		{"Bar", "b/libBar.so"},
+		{"z.so.1", "b/libz.so.1"},
+		{"z", "b/libz.so.1.10.0"},
+		{"Dangling", ""},
+		{"v", "b/libv.so"},
+		{"Nope", ""},
+		{"lib*oo*", "a/libFoo.so"},
+		{filepath.Join(root, "b", "libFoo.so"), "b/libFoo.so"},
+	} {
+		got := f.Find(test.name)
+		if got != "" {
+			got = rel(root, []string{got})[0]
+		}
+		if got != test.want {
+			t.Errorf("Find(%q): got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	root := mktree(t,
+		"a/libFoo.so",
+		"a/libFooTools.so",
+		"a/libFoo.txt",
+		"b/libFoo.so",
+		"b/libBarTools.so.2",
+		"a/libv.so.2",
+		"b/libv.so",
+	)
+	defer os.RemoveAll(root)
+
+	f := newLinuxFinder(root, "a", "b")
+	for _, test := range []struct {
+		name string
+		want []string
+	}{
+		{"Foo", []string{"a/libFoo.so", "b/libFoo.so"}},
+		{"Nope", []string{}},
+		{"*Tools*", []string{"a/libFooTools.so", "b/libBarTools.so.2"}},
+		{"libFoo*", []string{"a/libFoo.so", "a/libFooTools.so", "b/libFoo.so"}},
+		{"v", []string{"b/libv.so", "a/libv.so.2"}},
+	} {
+		got := rel(root, f.FindAll(test.name))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("FindAll(%q): got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	root := mktree(t,
+		"a/libFoo.so",
+		"a/libBar.so",
+		"a/libOnly.so",
+		"b/libFoo.so",
+		"b/libBar.so -> ../a/libBar.so",
+	)
+	defer os.RemoveAll(root)
+
+	f := newLinuxFinder(root, "a", "b")
+	got := f.Conflicts()
+	want := map[string][]string{
+		"libFoo.so": {
+			filepath.Join(root, "a", "libFoo.so"),
+			filepath.Join(root, "b", "libFoo.so"),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Conflicts: got %q, want %q", got, want)
+	}
+
+	libs := rel(root, f.Libs())
+	wantLibs := []string{"a/libBar.so", "a/libFoo.so", "a/libOnly.so", "b/libFoo.so"}
+	if !reflect.DeepEqual(libs, wantLibs) {
+		t.Errorf("Libs: got %q, want %q", libs, wantLibs)
+	}
+}
+
+func TestCache(t *testing.T) {
+	root := mktree(t, "a/libFoo.so")
+	defer os.RemoveAll(root)
+
+	f := newLinuxFinder(root, "a")
+	if got := f.Find("Foo"); got == "" {
+		t.Fatalf("Find(Foo): not found")
+	}
+	if len(f.dirs) != 0 {
+		t.Errorf("Find(Foo) read %d directory listings, want none", len(f.dirs))
+	}
+	if got := f.Find("Bar"); got != "" {
+		t.Fatalf("Find(Bar): got %q, want none", got)
+	}
+
+	err := ioutil.WriteFile(filepath.Join(root, "a", "libBar.so"), nil, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Find("Bar"); got != "" {
+		t.Errorf("Find(Bar) with a cached listing: got %q, want none", got)
+	}
+
+	f.Reset()
+	if got := f.Find("Bar"); got == "" {
+		t.Errorf("Find(Bar) after Reset: not found")
+	}
+}
+
+func TestKinds(t *testing.T) {
+	root := mktree(t,
+		"py1/Pkg/__init__.py",
+		"py1/Pkg/mod.py",
+		"py2/Pkg/__init__.py",
+		"py2/Pkg/sub/__init__.py",
+		"py2/Pkg/mod.py",
+		"py2/ext.so",
+		"py3/order/__init__.py",
+		"py3/order.so",
+		"py3/order.py",
+		"py3/ext.py",
+		"py3/ext.so",
+		"py3/src.pyc",
+		"py3/src.py",
+		"py3/cext.pyc",
+		"py3/cextmodule.so",
+		"py3/mod.py",
+		"py4/mod/__init__.py",
+		"ns1/Ns/a.py",
+		"ns2/Ns/b.py",
+		"jo/Hello/opts.py",
+		"data/file.root",
+	)
+	defer os.RemoveAll(root)
+
+	for _, test := range []struct {
+		kind *Kind
+		dirs []string
+		name string
+		want []string
+	}{
+		{Python, []string{"py1", "py2"}, "Pkg.mod", []string{"py1/Pkg/mod.py", "py2/Pkg/mod.py"}},
+		{Python, []string{"py1", "py2"}, "Pkg", []string{"py1/Pkg/__init__.py", "py2/Pkg/__init__.py"}},
+		{Python, []string{"py1", "py2"}, "Pkg.sub", []string{}},
+		{Python, []string{"py2", "py1"}, "Pkg.sub", []string{"py2/Pkg/sub/__init__.py"}},
+		{Python, []string{"py1", "py2"}, "ext", []string{"py2/ext.so"}},
+		{Python, []string{"py3"}, "order", []string{"py3/order/__init__.py"}},
+		{Python, []string{"py3"}, "ext", []string{"py3/ext.so"}},
+		{Python, []string{"py3"}, "src", []string{"py3/src.py"}},
+		{Python, []string{"py3"}, "cext", []string{"py3/cextmodule.so"}},
+		{Python, []string{"py3", "py4"}, "mod", []string{"py3/mod.py", "py4/mod/__init__.py"}},
+		{Python, []string{"py3", "py4"}, "mod.sub", []string{}},
+		{Python, []string{"ns1", "ns2"}, "Ns.b", []string{"ns2/Ns/b.py"}},
+		{Python, []string{"ns1", "ns2"}, "Ns", []string{}},
+		{Python, []string{"py1", "py2"}, "Pkg/mod.py", []string{"py1/Pkg/mod.py", "py2/Pkg/mod.py"}},
+		{JobOptions, []string{"data", "jo"}, "Hello/opts.py", []string{"jo/Hello/opts.py"}},
+		{Data, []string{"data", "jo"}, "file.root", []string{"data/file.root"}},
+		{Data, []string{"data", "jo"}, "opts.py", []string{}},
+		{&Kind{Name: "file"}, []string{"data", "jo"}, "file.root", []string{"data/file.root"}},
+		{&Kind{Name: "root file", Lookup: func(f *Finder, dir, name string) string {
+			return f.LookupFile(dir, name+".root")
+		}}, []string{"jo", "data"}, "file", []string{"data/file.root"}},
+	} {
+		f := newLinuxFinder(root, test.dirs...)
+		f.Kind = test.kind
+		got := rel(root, f.FindAll(test.name))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s %q: got %q, want %q", test.kind.Name, test.name, got, test.want)
+		}
+	}
+}
+
+func TestNaming(t *testing.T) {
+	for _, test := range []struct {
+		goos string
+		name string
+		want string
+	}{
+		{"linux", "Foo", "libFoo.so"},
+		{"linux", "libFoo.so", "libFoo.so"},
+		{"linux", "z.so.1", "libz.so.1"},
+		{"darwin", "Foo", "libFoo.dylib"},
+		{"darwin", "libFoo.1.dylib", "libFoo.1.dylib"},
+		{"windows", "Foo", "Foo.dll"},
+	} {
+		got := PlatformNaming(test.goos).LibName(test.name)
+		if got != test.want {
+			t.Errorf("%s LibName(%q): got %q, want %q", test.goos, test.name, got, test.want)
+		}
+	}
+
+	root := mktree(t, "lib/libFoo.1.dylib", "lib/libFoo.2.dylib")
+	defer os.RemoveAll(root)
+	f := newLinuxFinder(root, "lib")
+	f.Naming = PlatformNaming("darwin")
+	if got := rel(root, []string{f.Find("Foo")})[0]; got != "lib/libFoo.2.dylib" {
+		t.Errorf("darwin Find(Foo): got %q, want %q", got, "lib/libFoo.2.dylib")
+	}
+}
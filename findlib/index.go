@@ -0,0 +1,148 @@
+package findlib
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/atlas-org/scripts/internal/fileutil"
+)
+
+// indexVersion is the version of the on-disk index format.
+const indexVersion = 1
+
+// racyDelay is the time span within which a directory modification may not
+// change its modification time, on file systems with a coarse resolution.
+const racyDelay = 2 * time.Second
+
+// index is the on-disk form of the directory listings of a Finder.
+type index struct {
+	Version int                 `json:"version"`
+	Paths   []string            `json:"paths"`
+	Dirs    map[string]indexDir `json:"dirs"`
+}
+
+// indexDir is the on-disk form of a directory listing.
+type indexDir struct {
+	Mtime int64                  `json:"mtime"`
+	Names map[string]os.FileMode `json:"names"`
+}
+
+// IndexFile returns the name of the index file of the search path of f
+// inside the directory dir. Each search path gets its own index file.
+func (f *Finder) IndexFile(dir string) string {
+	sum := sha1.Sum([]byte(strings.Join(f.Paths, "\n")))
+	return filepath.Join(dir, "findlib-"+hex.EncodeToString(sum[:])+".json")
+}
+
+// LoadIndex fills the cached directory listings of f from the index file
+// fname. Listings of directories modified since the index was written are
+// discarded, and read again when needed.
+// A missing index file, or one written for another search path, is not an
+// error: the listings are then read from the directories.
+// Once LoadIndex was called, whole directory listings are read (and saved by
+// SaveIndex) even where single files are looked up.
+func (f *Finder) LoadIndex(fname string) error {
+	f.mu.Lock()
+	f.indexed = true
+	f.mu.Unlock()
+
+	buf, err := ioutil.ReadFile(fname)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var idx index
+	err = json.Unmarshal(buf, &idx)
+	if err != nil {
+		return err
+	}
+	if idx.Version != indexVersion || strings.Join(idx.Paths, "\n") != strings.Join(f.Paths, "\n") {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stale := false
+	for dir, d := range idx.Dirs {
+		if _, ok := f.dirs[dir]; ok {
+			continue
+		}
+		mtime := int64(0)
+		if fi, err := os.Stat(dir); err == nil {
+			mtime = fi.ModTime().UnixNano()
+		}
+		if mtime != d.Mtime {
+			stale = true
+			continue
+		}
+		l := &listing{
+			mtime: d.Mtime,
+			names: d.Names,
+			links: make(map[string]bool),
+		}
+		if l.names == nil {
+			l.names = make(map[string]os.FileMode)
+		}
+		f.cache(dir, l)
+	}
+	f.dirty = stale
+	return nil
+}
+
+// SaveIndex writes the cached directory listings of f into the index file
+// fname, if they changed since LoadIndex.
+func (f *Finder) SaveIndex(fname string) error {
+	f.mu.Lock()
+	dirty := f.dirty
+	f.mu.Unlock()
+	if !dirty {
+		if _, err := os.Stat(fname); err == nil {
+			return nil
+		}
+	}
+
+	f.mu.Lock()
+	idx := index{
+		Version: indexVersion,
+		Paths:   f.Paths,
+		Dirs:    make(map[string]indexDir, len(f.dirs)),
+	}
+	// listings of directories modified very recently could miss entries
+	// added within the same mtime tick: they are saved as stale.
+	racy := time.Now().Add(-racyDelay).UnixNano()
+	for dir, l := range f.dirs {
+		mtime := l.mtime
+		if mtime > racy {
+			mtime = -1
+		}
+		idx.Dirs[dir] = indexDir{Mtime: mtime, Names: l.names}
+	}
+	buf, err := json.Marshal(idx)
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(fname), 0755)
+	if err != nil {
+		return err
+	}
+	err = fileutil.WriteFile(fname, buf)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.dirty = false
+	f.mu.Unlock()
+	return nil
+}
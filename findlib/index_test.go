@@ -0,0 +1,88 @@
+package findlib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIndex(t *testing.T) {
+	root := mktree(t, "a/libFoo.so", "b/libBar.so")
+	defer os.RemoveAll(root)
+
+	// directories modified within racyDelay are never trusted
+	old := time.Now().Add(-time.Hour)
+	for _, dir := range []string{"a", "b"} {
+		err := os.Chtimes(filepath.Join(root, dir), old, old)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := newLinuxFinder(root, "a", "b")
+	fname := f.IndexFile(filepath.Join(root, "index"))
+	err := f.LoadIndex(fname)
+	if err != nil {
+		t.Fatalf("loading a missing index: %v", err)
+	}
+	if f.Find("Bar") == "" {
+		t.Fatalf("Find(Bar): not found")
+	}
+	err = f.SaveIndex(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sneak a library in, without changing the modification time: the
+	// indexed listing is used
+	err = ioutil.WriteFile(filepath.Join(root, "b", "libNew.so"), nil, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chtimes(filepath.Join(root, "b"), old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f = newLinuxFinder(root, "a", "b")
+	err = f.LoadIndex(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Find("New"); got != "" {
+		t.Errorf("Find(New) from the index: got %q, want none", got)
+	}
+	if got := f.Find("Bar"); got == "" {
+		t.Errorf("Find(Bar) from the index: not found")
+	}
+
+	// a modified directory is read again
+	now := time.Now().Add(-time.Minute)
+	err = os.Chtimes(filepath.Join(root, "b"), now, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f = newLinuxFinder(root, "a", "b")
+	err = f.LoadIndex(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Find("New"); got == "" {
+		t.Errorf("Find(New) after a modification: not found")
+	}
+
+	// another search path does not share the index
+	g := newLinuxFinder(root, "b")
+	if g.IndexFile(filepath.Join(root, "index")) == fname {
+		t.Errorf("IndexFile: same file for different search paths")
+	}
+	err = g.LoadIndex(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.dirs) != 0 {
+		t.Errorf("LoadIndex of another search path: got %d listings, want none", len(g.dirs))
+	}
+}
@@ -0,0 +1,180 @@
+package findlib
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Kind describes a kind of files and the search path they are looked up
+// along. Other kinds of files can be located by defining new Kinds.
+type Kind struct {
+	Name   string // kind of files, for messages ("library")
+	EnvVar string // variable holding the search path ("LD_LIBRARY_PATH")
+	Seps   string // separators of the search path entries
+
+	// Lookup returns the location of name inside dir, or "".
+	// Plain file names are looked up (Finder.LookupFile) if nil.
+	Lookup func(f *Finder, dir, name string) string
+
+	// Fallback, if not nil, returns the location of name inside dir, or "",
+	// when Lookup finds name in none of the directories of the search path.
+	Fallback func(f *Finder, dir, name string) string
+}
+
+// lookup returns the location of name inside dir, or "".
+func (k *Kind) lookup(f *Finder, dir, name string) string {
+	if k.Lookup == nil {
+		return f.LookupFile(dir, name)
+	}
+	return k.Lookup(f, dir, name)
+}
+
+var (
+	// Library locates shared libraries along LD_LIBRARY_PATH. Names are
+	// mapped to file names with the naming rules of the Finder
+	// (AthenaServices is libAthenaServices.so on linux). When no directory
+	// holds the library itself, the most recent of the versioned sonames of
+	// the first directory holding some is picked up.
+	Library = &Kind{
+		Name:     "library",
+		EnvVar:   "LD_LIBRARY_PATH",
+		Seps:     ":",
+		Lookup:   lookupLib,
+		Fallback: lookupVersionedLib,
+	}
+
+	// Python locates python modules (Pkg.module) along PYTHONPATH, as
+	// packages, extension modules or plain modules, the way import does.
+	Python = &Kind{
+		Name:   "python module",
+		EnvVar: "PYTHONPATH",
+		Seps:   ":",
+		Lookup: lookupPython,
+	}
+
+	// JobOptions locates job options along JOBOPTSEARCHPATH. Like the
+	// athena PathResolver, both ',' and ':' separate its entries.
+	JobOptions = &Kind{
+		Name:   "job options",
+		EnvVar: "JOBOPTSEARCHPATH",
+		Seps:   ",:",
+		Lookup: (*Finder).LookupFile,
+	}
+
+	// Data locates data files along DATAPATH.
+	Data = &Kind{
+		Name:   "data file",
+		EnvVar: "DATAPATH",
+		Seps:   ":",
+		Lookup: (*Finder).LookupFile,
+	}
+)
+
+// lookupLib returns the location of the library name inside dir.
+func lookupLib(f *Finder, dir, name string) string {
+	return f.LookupFile(dir, f.Naming.LibName(name))
+}
+
+// lookupVersionedLib returns the most recent versioned soname of the library
+// name inside dir. Runtime packages usually lack the unversioned libfoo.so
+// symlink. As the whole directory has to be read, this is only tried once
+// the library was looked up in all the directories of the search path.
+func lookupVersionedLib(f *Finder, dir, name string) string {
+	lib := f.Naming.LibName(name)
+	best := ""
+	var bestVers []int
+	for _, name := range f.names(dir) {
+		vers, ok := f.Naming.version(lib, name)
+		if !ok {
+			continue
+		}
+		fname := f.LookupFile(dir, name)
+		if fname == "" {
+			// dangling symlink
+			continue
+		}
+		if best == "" || lessVersion(bestVers, vers) {
+			best = fname
+			bestVers = vers
+		}
+	}
+	return best
+}
+
+// lookupPython returns the file implementing the python module name
+// (Pkg.module) inside dir. Names ending with .py are taken as file names.
+// Modules which python would not import, because a package shadows the one
+// holding them, are not reported in any directory: the copies found in
+// other directories are the ones python would import if those shadowing it
+// were removed.
+func lookupPython(f *Finder, dir, name string) string {
+	if strings.HasSuffix(name, ".py") {
+		return f.LookupFile(dir, name)
+	}
+	if f.importPython(f.Paths, name) == "" {
+		return ""
+	}
+	return f.importPython([]string{dir}, name)
+}
+
+// importPython returns the file python imports for the module name, along
+// the directories dirs, or "".
+// Like python, submodules are only searched inside the directory of their
+// package (its __path__): the first regular package found along dirs, or all
+// the portions of a namespace package if there is no regular one.
+func (f *Finder) importPython(dirs []string, name string) string {
+	comps := strings.Split(name, ".")
+	for i, comp := range comps {
+		last := i == len(comps)-1
+		portions := make([]string, 0)
+		found := ""
+		for _, dir := range dirs {
+			fname, pkg := f.pythonModule(dir, comp)
+			if fname != "" {
+				found = fname
+				if !last {
+					if !pkg {
+						// a plain module has no submodules
+						return ""
+					}
+					portions = []string{filepath.Dir(fname)}
+				}
+				break
+			}
+			if pkg {
+				portions = append(portions, filepath.Join(dir, comp))
+			}
+		}
+		if last {
+			return found
+		}
+		if len(portions) == 0 {
+			return ""
+		}
+		dirs = portions
+	}
+	return ""
+}
+
+// pythonModule returns the file implementing the module or package name
+// inside dir, in the order python 2 and 3 try them: regular package,
+// extension module, source then compiled file. pkg is whether name is a
+// package; dir/name is a namespace package portion if it is a directory
+// without __init__ file.
+func (f *Finder) pythonModule(dir, name string) (fname string, pkg bool) {
+	pkgdir := filepath.Join(dir, name)
+	if f.isDir(pkgdir) {
+		for _, init := range []string{"__init__.py", "__init__.pyc"} {
+			if fname := f.LookupFile(pkgdir, init); fname != "" {
+				return fname, true
+			}
+		}
+		pkg = true
+	}
+	for _, suffix := range []string{".so", "module.so", ".py", ".pyc"} {
+		if fname := f.LookupFile(dir, name+suffix); fname != "" {
+			return fname, false
+		}
+	}
+	return "", pkg
+}
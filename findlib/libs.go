@@ -0,0 +1,88 @@
+package findlib
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Glob returns all the libraries matching the shell-style pattern, in
+// search order. Matches within a directory are sorted by name. The library
+// prefix is added to the pattern if it lacks it (*Tools* is lib*Tools*).
+func (f *Finder) Glob(pattern string) []string {
+	if !strings.HasPrefix(pattern, f.Naming.Prefix) {
+		pattern = f.Naming.Prefix + pattern
+	}
+
+	o := make([]string, 0)
+	for _, dir := range f.Paths {
+		for _, name := range f.names(dir) {
+			if ok, _ := filepath.Match(pattern, name); !ok || !f.Naming.IsLib(name) {
+				continue
+			}
+			if fname := f.LookupFile(dir, name); fname != "" {
+				o = append(o, fname)
+			}
+		}
+	}
+	return o
+}
+
+// Libs returns all the libraries found along the search path, in search
+// order. Copies resolving to the same file are only listed once.
+func (f *Finder) Libs() []string {
+	seen := make(map[string]bool)
+	o := make([]string, 0)
+	for _, dir := range f.Paths {
+		l := f.list(dir)
+		for _, name := range f.names(dir) {
+			if l.names[name].IsDir() || !f.Naming.IsLib(name) {
+				continue
+			}
+			fname := filepath.Join(dir, name)
+			real, err := filepath.EvalSymlinks(fname)
+			if err != nil || seen[real] {
+				continue
+			}
+			seen[real] = true
+			o = append(o, fname)
+		}
+	}
+	return o
+}
+
+// Conflicts returns the libraries provided by more than one directory of the
+// search path, with their locations in search order: the first one is the
+// one the dynamic loader picks up.
+// Copies resolving to the same file are not considered as conflicting.
+func (f *Finder) Conflicts() map[string][]string {
+	locs := make(map[string][]string)
+	reals := make(map[string]map[string]bool)
+	for _, dir := range f.Paths {
+		l := f.list(dir)
+		for _, name := range f.names(dir) {
+			if l.names[name].IsDir() || !f.Naming.IsLib(name) {
+				continue
+			}
+			fname := filepath.Join(dir, name)
+			real, err := filepath.EvalSymlinks(fname)
+			if err != nil {
+				real = fname
+			}
+			if reals[name] == nil {
+				reals[name] = make(map[string]bool)
+			}
+			if reals[name][real] {
+				continue
+			}
+			reals[name][real] = true
+			locs[name] = append(locs[name], fname)
+		}
+	}
+
+	for name, fnames := range locs {
+		if len(fnames) < 2 {
+			delete(locs, name)
+		}
+	}
+	return locs
+}
@@ -0,0 +1,116 @@
+package findlib
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Naming holds the rules mapping OS-independent library names
+// (AthenaServices) to library file names (libAthenaServices.so).
+type Naming struct {
+	Prefix string // prefix of library file names ("lib")
+	Suffix string // suffix of library file names (".so")
+}
+
+// namings holds the naming rules of the supported platforms.
+var namings = map[string]Naming{
+	"linux":   {Prefix: "lib", Suffix: ".so"},
+	"darwin":  {Prefix: "lib", Suffix: ".dylib"},
+	"windows": {Prefix: "", Suffix: ".dll"},
+}
+
+// NativeNaming returns the naming rules of the running platform.
+func NativeNaming() Naming {
+	return PlatformNaming(runtime.GOOS)
+}
+
+// PlatformNaming returns the naming rules of the platform goos (as in
+// runtime.GOOS). Unknown platforms follow the linux rules.
+func PlatformNaming(goos string) Naming {
+	if n, ok := namings[goos]; ok {
+		return n
+	}
+	return namings["linux"]
+}
+
+// LibName returns the library file name of name.
+// Names already carrying the prefix or the suffix are left alone, and
+// versioned sonames (libz.so.1) are kept as is.
+func (n Naming) LibName(name string) string {
+	if !strings.HasPrefix(name, n.Prefix) {
+		name = n.Prefix + name
+	}
+	if !strings.HasSuffix(name, n.Suffix) && !strings.Contains(name, n.Suffix+".") {
+		name = name + n.Suffix
+	}
+	return name
+}
+
+// IsLib returns whether the file name fname is the one of a library,
+// versioned or not.
+func (n Naming) IsLib(fname string) bool {
+	if !strings.HasPrefix(fname, n.Prefix) {
+		return false
+	}
+	return strings.HasSuffix(fname, n.Suffix) || strings.Contains(fname, n.Suffix+".")
+}
+
+// version returns the version numbers of fname if it is a versioned soname
+// of the library file lib (libfoo.so.N.M on linux, libfoo.N.M.dylib on
+// darwin).
+func (n Naming) version(lib, fname string) ([]int, bool) {
+	if n.Suffix == "" || !strings.HasSuffix(lib, n.Suffix) {
+		return nil, false
+	}
+
+	var vers string
+	switch {
+	case strings.HasPrefix(fname, lib+"."):
+		vers = fname[len(lib)+1:]
+	case strings.HasSuffix(fname, n.Suffix):
+		base := strings.TrimSuffix(lib, n.Suffix) + "."
+		if !strings.HasPrefix(fname, base) {
+			return nil, false
+		}
+		vers = strings.TrimSuffix(fname[len(base):], n.Suffix)
+	default:
+		return nil, false
+	}
+
+	v := parseVersion(vers)
+	return v, v != nil
+}
+
+// parseVersion parses a dotted version ("3.4.20"), returning nil if it is
+// not made of numbers only.
+func parseVersion(v string) []int {
+	o := make([]int, 0)
+	for _, tok := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(tok)
+		if err != nil || n < 0 {
+			return nil
+		}
+		o = append(o, n)
+	}
+	return o
+}
+
+// formatVersion is the inverse of parseVersion.
+func formatVersion(v []int) string {
+	toks := make([]string, len(v))
+	for i, n := range v {
+		toks[i] = strconv.Itoa(n)
+	}
+	return strings.Join(toks, ".")
+}
+
+// lessVersion returns whether version a is older than version b.
+func lessVersion(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
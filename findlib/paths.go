@@ -0,0 +1,163 @@
+package findlib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Locations of the configuration and cache of the dynamic loader.
+const (
+	LdSoConfFile  = "/etc/ld.so.conf"
+	LdSoCacheFile = "/etc/ld.so.cache"
+)
+
+// SplitPathList returns the non-empty entries of the path list v, split on
+// any of the separators seps.
+func SplitPathList(v, seps string) []string {
+	return strings.FieldsFunc(v, func(r rune) bool {
+		return strings.ContainsRune(seps, r)
+	})
+}
+
+// UniquePaths removes the duplicate entries of paths, keeping the first one.
+func UniquePaths(paths []string) []string {
+	seen := make(map[string]bool)
+	o := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		o = append(o, p)
+	}
+	return o
+}
+
+// SystemPaths returns the directories searched by the dynamic loader after
+// LD_LIBRARY_PATH: the ones of /etc/ld.so.conf, of the ld.so cache and the
+// default ones.
+func SystemPaths() []string {
+	paths := LdSoConf(LdSoConfFile)
+	paths = append(paths, LdSoCache(LdSoCacheFile)...)
+	paths = append(paths, "/lib64", "/usr/lib64", "/lib", "/usr/lib")
+	return UniquePaths(paths)
+}
+
+// LdSoConf returns the directories listed in the ld.so.conf file fname,
+// following its include directives.
+func LdSoConf(fname string) []string {
+	return ldSoConf(fname, make(map[string]bool))
+}
+
+func ldSoConf(fname string, seen map[string]bool) []string {
+	if seen[fname] {
+		return nil
+	}
+	seen[fname] = true
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	o := make([]string, 0)
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		line := scan.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "include") && len(line) > len("include") &&
+			(line[len("include")] == ' ' || line[len("include")] == '\t'):
+			for _, pattern := range strings.Fields(line[len("include"):]) {
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(filepath.Dir(fname), pattern)
+				}
+				matches, _ := filepath.Glob(pattern)
+				for _, m := range matches {
+					o = append(o, ldSoConf(m, seen)...)
+				}
+			}
+		case strings.HasPrefix(line, "hwcap ") || strings.HasPrefix(line, "hwcap\t"):
+			// not a directory
+		default:
+			// old-style "dir=type" entries
+			if i := strings.Index(line, "="); i >= 0 {
+				line = strings.TrimSpace(line[:i])
+			}
+			o = append(o, line)
+		}
+	}
+	return o
+}
+
+// LdSoCache returns the directories of the libraries registered in the
+// ld.so cache file fname, in order of appearance.
+// Both the new (glibc-ld.so.cache1.1) and the old (ld.so-1.7.0) formats are
+// understood. The cache is assumed to be little-endian.
+func LdSoCache(fname string) []string {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil
+	}
+
+	const (
+		newMagic = "glibc-ld.so.cache1.1"
+		oldMagic = "ld.so-1.7.0"
+	)
+
+	var (
+		base    int // offset of the string table origin
+		entries int // offset of the first entry
+		size    int // size of an entry
+		nlibs   int
+	)
+	order := binary.LittleEndian
+	switch i := bytes.Index(data, []byte(newMagic)); {
+	case i >= 0 && len(data) >= i+48:
+		nlibs = int(order.Uint32(data[i+20:]))
+		base = i
+		entries = i + 48
+		size = 24
+	case bytes.HasPrefix(data, []byte(oldMagic)) && len(data) >= 16:
+		nlibs = int(order.Uint32(data[12:]))
+		entries = 16
+		size = 12
+		base = entries + nlibs*size
+	default:
+		return nil
+	}
+
+	o := make([]string, 0)
+	seen := make(map[string]bool)
+	for i := 0; i < nlibs; i++ {
+		beg := entries + i*size
+		if beg+size > len(data) {
+			break
+		}
+		value := base + int(order.Uint32(data[beg+8:]))
+		if value < 0 || value >= len(data) {
+			continue
+		}
+		end := bytes.IndexByte(data[value:], 0)
+		if end < 0 {
+			continue
+		}
+		dir := filepath.Dir(string(data[value : value+end]))
+		if !seen[dir] {
+			seen[dir] = true
+			o = append(o, dir)
+		}
+	}
+	return o
+}
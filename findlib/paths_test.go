@@ -0,0 +1,121 @@
+package findlib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitPathList(t *testing.T) {
+	for _, test := range []struct {
+		v    string
+		seps string
+		want []string
+	}{
+		{"", ":", []string{}},
+		{"/a:/b::/c:", ":", []string{"/a", "/b", "/c"}},
+		{",/a,/b:/c", ",:", []string{"/a", "/b", "/c"}},
+	} {
+		got := SplitPathList(test.v, test.seps)
+		if len(got) == 0 && len(test.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SplitPathList(%q, %q): got %q, want %q", test.v, test.seps, got, test.want)
+		}
+	}
+
+	got := UniquePaths([]string{"/a", "/b/", "/a/", "/c", "/b"})
+	want := []string{"/a", "/b", "/c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UniquePaths: got %q, want %q", got, want)
+	}
+}
+
+func TestLdSoConf(t *testing.T) {
+	root := mktree(t)
+	defer os.RemoveAll(root)
+
+	for name, content := range map[string]string{
+		"ld.so.conf": "# comment\n" +
+			"include ld.so.conf.d/*.conf\n" +
+			"/usr/local/lib # trailing comment\n" +
+			"hwcap 0 nosegneg\n" +
+			"/usr/lib/old=libc5\n",
+		"ld.so.conf.d/a.conf": "/opt/a/lib\n\n",
+		"ld.so.conf.d/b.conf": "/opt/b/lib\ninclude ../ld.so.conf\n",
+	} {
+		fname := filepath.Join(root, name)
+		err := os.MkdirAll(filepath.Dir(fname), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(fname, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := LdSoConf(filepath.Join(root, "ld.so.conf"))
+	want := []string{"/opt/a/lib", "/opt/b/lib", "/usr/local/lib", "/usr/lib/old"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LdSoConf: got %q, want %q", got, want)
+	}
+}
+
+func TestLdSoCache(t *testing.T) {
+	libs := []string{
+		"/lib64/libc.so.6",
+		"/usr/lib64/libz.so.1",
+		"/lib64/libm.so.6",
+	}
+
+	// new format: header, entries, then the string table
+	const (
+		hdrsize   = 48
+		entrysize = 24
+	)
+	order := binary.LittleEndian
+	strs := new(bytes.Buffer)
+	entries := make([]byte, entrysize*len(libs))
+	base := hdrsize + len(entries)
+	for i, lib := range libs {
+		order.PutUint32(entries[i*entrysize+8:], uint32(base+strs.Len()))
+		strs.WriteString(lib)
+		strs.WriteByte(0)
+	}
+	hdr := make([]byte, hdrsize)
+	copy(hdr, "glibc-ld.so.cache1.1")
+	order.PutUint32(hdr[20:], uint32(len(libs)))
+	order.PutUint32(hdr[24:], uint32(strs.Len()))
+
+	f, err := ioutil.TempFile("", "findlib-ld.so.cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	for _, buf := range [][]byte{hdr, entries, strs.Bytes()} {
+		_, err = f.Write(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := LdSoCache(f.Name())
+	want := []string{"/lib64", "/usr/lib64"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LdSoCache: got %q, want %q", got, want)
+	}
+
+	if got := LdSoCache(filepath.Join(os.TempDir(), "findlib-no-such-cache")); len(got) != 0 {
+		t.Errorf("LdSoCache of a missing file: got %q, want none", got)
+	}
+}
@@ -0,0 +1,185 @@
+package findlib
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Provider is a library declared as providing a component or a class.
+type Provider struct {
+	Lib  string // library name, as declared
+	Decl string // file holding the declaration
+	Path string // location of the library, "" if not found
+}
+
+// declParser returns the libraries declared by the file fname as providing
+// name.
+type declParser func(fname, name string) []string
+
+// providers scans the files of the search path with one of the given
+// extensions for libraries declared as providing name, in search order.
+func (f *Finder) providers(name string, parsers map[string]declParser) []Provider {
+	o := make([]Provider, 0)
+	for _, dir := range f.Paths {
+		l := f.list(dir)
+		for _, fname := range f.names(dir) {
+			parse, ok := parsers[filepath.Ext(fname)]
+			if l.names[fname].IsDir() || !ok {
+				continue
+			}
+			decl := filepath.Join(dir, fname)
+			for _, lib := range parse(decl, name) {
+				o = append(o, Provider{
+					Lib:  lib,
+					Decl: decl,
+					Path: f.resolveProvider(lib, dir),
+				})
+			}
+		}
+	}
+	return o
+}
+
+// resolveProvider locates a library declared in dir: next to the
+// declaration first, then along the search path.
+func (f *Finder) resolveProvider(lib, dir string) string {
+	if strings.Contains(lib, "/") {
+		if f.exists(lib) {
+			return lib
+		}
+		return ""
+	}
+	if fname := lookupLib(f, dir, lib); fname != "" {
+		return fname
+	}
+	if fname := lookupVersionedLib(f, dir, lib); fname != "" {
+		return fname
+	}
+	if libs := f.lookup(Library, lib, true); len(libs) > 0 {
+		return libs[0]
+	}
+	return ""
+}
+
+// FindComponent returns the libraries declaring the Gaudi component name in
+// the *.components or *.confdb files of the search path, in search order.
+func (f *Finder) FindComponent(name string) []Provider {
+	return f.providers(name, map[string]declParser{
+		".components": componentsLibs,
+		".confdb":     confdbLibs,
+	})
+}
+
+// FindClass returns the libraries declaring the dictionary of the class
+// name in the *.rootmap files of the search path, in search order.
+func (f *Finder) FindClass(name string) []Provider {
+	return f.providers(name, map[string]declParser{
+		".rootmap": rootmapLibs,
+	})
+}
+
+// scanLines calls fn on each non-empty, non-comment line of fname.
+func scanLines(fname string, fn func(line string)) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fn(line)
+	}
+}
+
+// componentsLibs parses a Gaudi *.components file, made of
+// "[vN::]LIBRARY:COMPONENT" lines.
+func componentsLibs(fname, name string) []string {
+	o := make([]string, 0)
+	scanLines(fname, func(line string) {
+		if i := strings.Index(line, "::"); i >= 0 && strings.HasPrefix(line, "v") &&
+			!strings.Contains(line[:i], ":") {
+			line = line[i+2:]
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return
+		}
+		if line[i+1:] == name {
+			o = append(o, line[:i])
+		}
+	})
+	return o
+}
+
+// confdbLibs parses a Gaudi *.confdb file, made of
+// "MODULE CONFIGURABLE LIBRARY" lines. Configurables are named after their
+// component, with "::" replaced by "__".
+func confdbLibs(fname, name string) []string {
+	cfg := strings.Replace(name, "::", "__", -1)
+	o := make([]string, 0)
+	scanLines(fname, func(line string) {
+		toks := strings.Fields(line)
+		if len(toks) != 3 {
+			return
+		}
+		if toks[1] == name || toks[1] == cfg {
+			o = append(o, toks[2])
+		}
+	})
+	return o
+}
+
+// normClass returns the class name without any whitespace, so template
+// names compare equal whatever their spelling.
+func normClass(name string) string {
+	return strings.Join(strings.Fields(name), "")
+}
+
+// rootmapLibs parses a ROOT *.rootmap file, in either of its formats: the
+// ROOT5 one, made of "Library.CLASS: LIBRARY DEPS..." lines (with "::"
+// encoded as "@@" and spaces as "-" in CLASS), or the ROOT6 one, made of
+// "[ LIBRARY DEPS... ]" sections followed by "class CLASS" or
+// "typedef CLASS" lines.
+// The first library of each declaration is the one owning the dictionary.
+func rootmapLibs(fname, name string) []string {
+	name = normClass(name)
+	o := make([]string, 0)
+	section := ""
+	scanLines(fname, func(line string) {
+		switch {
+		case strings.HasPrefix(line, "["):
+			toks := strings.Fields(strings.Trim(line, "[]"))
+			section = ""
+			if len(toks) > 0 {
+				section = toks[0]
+			}
+
+		case strings.HasPrefix(line, "Library."):
+			i := strings.Index(line, ": ")
+			if i < 0 {
+				return
+			}
+			class := line[len("Library."):i]
+			class = strings.Replace(class, "@@", "::", -1)
+			class = strings.Replace(class, "-", " ", -1)
+			libs := strings.Fields(line[i+2:])
+			if len(libs) > 0 && normClass(class) == name {
+				o = append(o, libs[0])
+			}
+
+		case strings.HasPrefix(line, "class ") || strings.HasPrefix(line, "typedef "):
+			i := strings.Index(line, " ")
+			if section != "" && normClass(line[i+1:]) == name {
+				o = append(o, section)
+			}
+		}
+	})
+	return o
+}
@@ -0,0 +1,87 @@
+package findlib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProviders(t *testing.T) {
+	root := mktree(t,
+		"a/libGaudiCoreSvc.so",
+		"a/libxAODJetDict.so",
+		"b/libAthenaServices.so",
+	)
+	defer os.RemoveAll(root)
+
+	for name, content := range map[string]string{
+		"a/GaudiCoreSvc.components": "v2::libGaudiCoreSvc.so:JobOptionsSvc\n" +
+			"v2::libGaudiCoreSvc.so:Gaudi::Hive\n",
+		"a/GaudiCoreSvc.confdb": "# generated\n" +
+			"GaudiCoreSvc.GaudiCoreSvcConf Gaudi__Other libGaudiCoreSvc.so\n",
+		"a/xAODJet.rootmap": "[ libxAODJetDict.so libxAODBase.so ]\n" +
+			"# List of selected classes\n" +
+			"class xAOD::Jet_v1\n" +
+			"typedef xAOD::Jet\n",
+		"b/AthenaServices.components": "libAthenaServices.so:JobOptionsSvc\n",
+		"b/Old.rootmap":               "Library.std@@vector<xAOD@@Jet_v1-*>: libOldDict.so libz.so\n",
+	} {
+		err := ioutil.WriteFile(filepath.Join(root, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := newLinuxFinder(root, "a", "b")
+	lib := func(name string) string {
+		if name == "" {
+			return ""
+		}
+		return filepath.Join(root, name)
+	}
+	for _, test := range []struct {
+		name string
+		find func(string) []Provider
+		want []Provider
+	}{
+		{
+			"JobOptionsSvc", f.FindComponent,
+			[]Provider{
+				{"libGaudiCoreSvc.so", lib("a/GaudiCoreSvc.components"), lib("a/libGaudiCoreSvc.so")},
+				{"libAthenaServices.so", lib("b/AthenaServices.components"), lib("b/libAthenaServices.so")},
+			},
+		},
+		{
+			"Gaudi::Hive", f.FindComponent,
+			[]Provider{
+				{"libGaudiCoreSvc.so", lib("a/GaudiCoreSvc.components"), lib("a/libGaudiCoreSvc.so")},
+			},
+		},
+		{
+			"Gaudi::Other", f.FindComponent,
+			[]Provider{
+				{"libGaudiCoreSvc.so", lib("a/GaudiCoreSvc.confdb"), lib("a/libGaudiCoreSvc.so")},
+			},
+		},
+		{
+			"xAOD::Jet", f.FindClass,
+			[]Provider{
+				{"libxAODJetDict.so", lib("a/xAODJet.rootmap"), lib("a/libxAODJetDict.so")},
+			},
+		},
+		{
+			"std::vector<xAOD::Jet_v1 *>", f.FindClass,
+			[]Provider{
+				{"libOldDict.so", lib("b/Old.rootmap"), ""},
+			},
+		},
+		{"Nope", f.FindClass, []Provider{}},
+	} {
+		got := test.find(test.name)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
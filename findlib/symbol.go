@@ -0,0 +1,150 @@
+package findlib
+
+import (
+	"debug/elf"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// symMatcher matches the dynamic symbols of a library against a query.
+type symMatcher struct {
+	exact   string // mangled (or C) symbol name
+	encoded string // Itanium encoding of a demangled C++ qualified name
+}
+
+// newSymMatcher returns a matcher for a mangled symbol name (_ZN...) or a
+// demangled C++ qualified name (ns::Class::method).
+// A demangled query matches the symbols of that name and of its members.
+// A name without "::" is also matched as a C symbol name.
+func newSymMatcher(query string) symMatcher {
+	query = strings.TrimSpace(query)
+	if i := strings.Index(query, "("); i >= 0 {
+		query = query[:i]
+	}
+	if strings.HasPrefix(query, "_Z") {
+		return symMatcher{exact: query}
+	}
+	m := symMatcher{}
+	toks := strings.Split(query, "::")
+	if len(toks) == 1 {
+		m.exact = query
+	}
+	enc := ""
+	if toks[0] == "std" {
+		// std:: is abbreviated as St
+		enc = "St"
+		toks = toks[1:]
+	}
+	for _, tok := range toks {
+		if tok == "" {
+			return m
+		}
+		enc += strconv.Itoa(len(tok)) + tok
+	}
+	m.encoded = enc
+	return m
+}
+
+func (m symMatcher) match(sym string) bool {
+	if m.exact != "" && sym == m.exact {
+		return true
+	}
+	return m.encoded != "" && strings.HasPrefix(leadingName(sym), m.encoded)
+}
+
+// leadingName returns the encoding of the name a mangled symbol is about,
+// followed by the rest of the symbol, or "" if sym is not such a symbol:
+// the (nested) name of a function or variable, or the class of a vtable,
+// typeinfo or typeinfo name. Names appearing in argument or template types
+// are not leading names.
+func leadingName(sym string) string {
+	switch {
+	case strings.HasPrefix(sym, "_ZN"):
+		// skip the cv and ref qualifiers of methods
+		return strings.TrimLeft(sym[len("_ZN"):], "rVKRO")
+	case strings.HasPrefix(sym, "_ZTV"), strings.HasPrefix(sym, "_ZTI"), strings.HasPrefix(sym, "_ZTS"):
+		return strings.TrimPrefix(sym[len("_ZTV"):], "N")
+	case strings.HasPrefix(sym, "_ZT"), strings.HasPrefix(sym, "_ZG"):
+		// thunks, guard variables, ...
+		return ""
+	case strings.HasPrefix(sym, "_Z"):
+		return sym[len("_Z"):]
+	}
+	return ""
+}
+
+// SymbolHits holds the symbols of a library matching a query.
+type SymbolHits struct {
+	Lib        string   // location of the library
+	Defined    []string // matching symbols defined by the library
+	Referenced []string // matching symbols the library needs
+	Err        error    // error reading the symbols of the library
+}
+
+// scanSymbols returns the matching symbols defined and referenced by the
+// library fname.
+func scanSymbols(fname string, m symMatcher) SymbolHits {
+	hits := SymbolHits{Lib: fname}
+	f, err := elf.Open(fname)
+	if err != nil {
+		hits.Err = err
+		return hits
+	}
+	defer f.Close()
+
+	syms, err := f.DynamicSymbols()
+	if err != nil {
+		hits.Err = err
+		return hits
+	}
+	for _, sym := range syms {
+		if !m.match(sym.Name) {
+			continue
+		}
+		if sym.Section == elf.SHN_UNDEF {
+			hits.Referenced = append(hits.Referenced, sym.Name)
+		} else {
+			hits.Defined = append(hits.Defined, sym.Name)
+		}
+	}
+	return hits
+}
+
+// FindSymbol scans the dynamic symbol tables of all the libraries of the
+// search path for the symbol query, which is either a mangled (or C) symbol
+// name or a demangled C++ qualified name (ns::Class::method). A demangled
+// query matches the symbols of that name and of its members (methods,
+// vtable, typeinfo, ...), not the ones merely using it in their arguments.
+// Only the libraries defining or referencing a matching symbol are returned,
+// in search order.
+func (f *Finder) FindSymbol(query string) []SymbolHits {
+	m := newSymMatcher(query)
+	libs := f.Libs()
+
+	throttle := make(chan struct{}, runtime.NumCPU())
+	ch := make(chan SymbolHits)
+	for _, lib := range libs {
+		go func(lib string) {
+			throttle <- struct{}{}
+			defer func() { <-throttle }()
+			ch <- scanSymbols(lib, m)
+		}(lib)
+	}
+
+	hits := make(map[string]SymbolHits, len(libs))
+	for range libs {
+		h := <-ch
+		hits[h.Lib] = h
+	}
+
+	// preserve the search order
+	o := make([]SymbolHits, 0)
+	for _, lib := range libs {
+		h := hits[lib]
+		if len(h.Defined) > 0 || len(h.Referenced) > 0 {
+			o = append(o, h)
+		}
+	}
+	return o
+}
@@ -0,0 +1,40 @@
+package findlib
+
+import (
+	"testing"
+)
+
+func TestSymMatcher(t *testing.T) {
+	for _, test := range []struct {
+		query string
+		sym   string
+		want  bool
+	}{
+		{"AthenaKernel::IProxyDict", "_ZN12AthenaKernel10IProxyDictD2Ev", true},
+		{"AthenaKernel::IProxyDict", "_ZNK12AthenaKernel10IProxyDict4nameEv", true},
+		{"AthenaKernel::IProxyDict", "_ZTVN12AthenaKernel10IProxyDictE", true},
+		{"AthenaKernel::IProxyDict", "_ZTIN12AthenaKernel10IProxyDictE", true},
+		{"AthenaKernel::IProxyDict", "_ZTSN12AthenaKernel10IProxyDictE", true},
+		{"AthenaKernel::IProxyDict", "_ZN3Foo3barEPN12AthenaKernel10IProxyDictE", false},
+		{"AthenaKernel::IProxyDict", "_ZN14AthenaKernel10IProxyDictD2Ev", false},
+		{"AthenaKernel::IProxyDict", "_ZThn8_N12AthenaKernel10IProxyDictD1Ev", false},
+		{"AthenaKernel::IProxyDict::name()", "_ZNK12AthenaKernel10IProxyDict4nameEv", true},
+		{"AthenaKernel::IProxyDict::name", "_ZN12AthenaKernel10IProxyDictD2Ev", false},
+		{"IProxyDict", "_ZTV10IProxyDict", true},
+		{"IProxyDict", "_ZN10IProxyDict5proxyEj", true},
+		{"IProxyDict", "_ZN3Foo3barEP10IProxyDict", false},
+		{"IProxyDict", "IProxyDict", true},
+		{"malloc", "malloc", true},
+		{"malloc", "malloc_trim", false},
+		{"foo", "_Z3fooi", true},
+		{"std::vector", "_ZNSt6vectorIiSaIiEE9push_backEOi", true},
+		{"std::endl", "_ZSt4endlIcSt11char_traitsIcEERSt13basic_ostreamIT_T0_ES6_", true},
+		{"_ZN12AthenaKernel10IProxyDictD2Ev", "_ZN12AthenaKernel10IProxyDictD2Ev", true},
+		{"_ZN12AthenaKernel10IProxyDictD2Ev", "_ZN12AthenaKernel10IProxyDictD1Ev", false},
+	} {
+		got := newSymMatcher(test.query).match(test.sym)
+		if got != test.want {
+			t.Errorf("%q matches %q: got %v, want %v", test.query, test.sym, got, test.want)
+		}
+	}
+}
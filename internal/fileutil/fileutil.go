@@ -0,0 +1,51 @@
+// Package fileutil holds file helpers shared by the cmtenv and findlib
+// packages. It only depends on the standard library, so findlib stays
+// importable without the CMT tooling.
+package fileutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile atomically replaces the content of fname with buf, so readers
+// never see a partial file. fname keeps its permissions if it exists, and is
+// created with 0644 otherwise.
+// A symlinked fname is resolved first, so its target is replaced rather
+// than the link itself.
+func WriteFile(fname string, buf []byte) error {
+	if target, err := filepath.EvalSymlinks(fname); err == nil {
+		fname = target
+	}
+
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(fname); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(fname), "."+filepath.Base(fname)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	_, err = f.Write(buf)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(f.Name(), mode)
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), fname)
+}